@@ -0,0 +1,131 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/rand"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// benchmarkFileSize is the size of the test file written by
+// libst_benchmark_folder_path.
+const benchmarkFileSize = 1 << 20
+
+// pathBenchmark is the result of libst_benchmark_folder_path. Durations are
+// in milliseconds and rates in bytes per second.
+type pathBenchmark struct {
+	Path                string  `json:"path"`
+	Success             bool    `json:"success"`
+	Error               string  `json:"error,omitempty"`
+	CreateLatencyMs     float64 `json:"createLatencyMs"`
+	WriteMs             float64 `json:"writeMs"`
+	ReadMs              float64 `json:"readMs"`
+	DeleteMs            float64 `json:"deleteMs"`
+	WriteBytesPerSecond float64 `json:"writeBytesPerSecond"`
+	ReadBytesPerSecond  float64 `json:"readBytesPerSecond"`
+}
+
+// libst_benchmark_folder_path tests whether the given directory is writable
+// and roughly how fast it is by writing, reading back and deleting a 1 MiB
+// test file. It returns a JSON object with "success", the "error" if not
+// successful, the time to create the file ("createLatencyMs"), the times to
+// write (including syncing to disk), read and delete it and the resulting
+// write and read rates. It doesn't require Syncthing to be running.
+//
+//export libst_benchmark_folder_path
+func libst_benchmark_folder_path(path string) *C.char {
+	res := pathBenchmark{Path: path}
+	if err := benchmarkPath(fs.NewFilesystem(fs.FilesystemTypeBasic, path), &res); err != nil {
+		res.Error = err.Error()
+	} else {
+		res.Success = true
+	}
+	return jsonString(res)
+}
+
+func benchmarkPath(filesystem fs.Filesystem, res *pathBenchmark) error {
+	if info, err := filesystem.Stat("."); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return errors.New("not a directory")
+	}
+
+	// Use a name which Syncthing treats as temporary file so it is ignored
+	// should it be left over.
+	name := fs.TempName("benchmark-" + rand.String(8))
+	data := make([]byte, benchmarkFileSize)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	fd, err := filesystem.Create(name)
+	if err != nil {
+		return err
+	}
+	res.CreateLatencyMs = milliseconds(time.Since(start))
+	defer filesystem.Remove(name)
+
+	start = time.Now()
+	_, err = fd.Write(data)
+	if err == nil {
+		err = fd.Sync()
+	}
+	if closeErr := fd.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	res.WriteMs = milliseconds(elapsed)
+	res.WriteBytesPerSecond = bytesPerSecond(len(data), elapsed)
+
+	start = time.Now()
+	fd, err = filesystem.Open(name)
+	if err != nil {
+		return err
+	}
+	read, err := ioutil.ReadAll(fd)
+	fd.Close()
+	if err != nil {
+		return err
+	}
+	elapsed = time.Since(start)
+	if !bytes.Equal(read, data) {
+		return errors.New("data read back differs from data written")
+	}
+	res.ReadMs = milliseconds(elapsed)
+	res.ReadBytesPerSecond = bytesPerSecond(len(read), elapsed)
+
+	start = time.Now()
+	if err := filesystem.Remove(name); err != nil {
+		return err
+	}
+	res.DeleteMs = milliseconds(time.Since(start))
+	return nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return d.Seconds() * 1000
+}
+
+func bytesPerSecond(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
@@ -0,0 +1,131 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+#include "c_bindings.h"
+
+static libst_logging_callback_function_t libst_logging_callback_function = NULL;
+static libst_database_repair_callback_function_t libst_database_repair_callback_function = NULL;
+static libst_event_callback_function_t libst_event_callback_function = NULL;
+static libst_startup_complete_callback_function_t libst_startup_complete_callback_function = NULL;
+static libst_crash_callback_function_t libst_crash_callback_function = NULL;
+
+void libst_set_logging_callback(libst_logging_callback_function_t callback)
+{
+	libst_logging_callback_function = callback;
+}
+
+void libst_invoke_logging_callback(int logLevel, const char *msg, size_t msgSize)
+{
+	if (libst_logging_callback_function) {
+		libst_logging_callback_function(logLevel, msg, msgSize);
+	}
+}
+
+void libst_set_database_repair_callback(libst_database_repair_callback_function_t callback)
+{
+	libst_database_repair_callback_function = callback;
+}
+
+void libst_invoke_database_repair_callback(int stage, const char *location, size_t locationSize)
+{
+	if (libst_database_repair_callback_function) {
+		libst_database_repair_callback_function(stage, location, locationSize);
+	}
+}
+
+void libst_invoke_folder_eta_callback(libst_folder_eta_callback_function_t callback, const char *folderID, size_t folderIDSize, long long bytesDone, long long bytesTotal, double bytesPerSecond)
+{
+	if (callback) {
+		callback(folderID, folderIDSize, bytesDone, bytesTotal, bytesPerSecond);
+	}
+}
+
+void libst_invoke_node_state_callback(libst_node_state_callback_function_t callback, int state)
+{
+	if (callback) {
+		callback(state);
+	}
+}
+
+void libst_invoke_scan_completed_callback(libst_scan_completed_callback_function_t callback, const char *folderID, size_t folderIDSize, int added, int modified, int deleted)
+{
+	if (callback) {
+		callback(folderID, folderIDSize, added, modified, deleted);
+	}
+}
+
+void libst_invoke_low_space_callback(libst_low_space_callback_function_t callback, const char *folderID, size_t folderIDSize, bool low, long long freeBytes)
+{
+	if (callback) {
+		callback(folderID, folderIDSize, low, freeBytes);
+	}
+}
+
+void libst_invoke_connection_error_callback(libst_connection_error_callback_function_t callback, const char *deviceID, size_t deviceIDSize, const char *address, size_t addressSize, const char *errorClass, size_t errorClassSize, const char *msg, size_t msgSize)
+{
+	if (callback) {
+		callback(deviceID, deviceIDSize, address, addressSize, errorClass, errorClassSize, msg, msgSize);
+	}
+}
+
+void libst_set_event_callback(libst_event_callback_function_t callback)
+{
+	libst_event_callback_function = callback;
+}
+
+void libst_invoke_event_callback(int handle, int eventType, const char *jsonData, size_t length)
+{
+	if (libst_event_callback_function) {
+		libst_event_callback_function(handle, eventType, jsonData, length);
+	}
+}
+
+void libst_set_startup_complete_callback(libst_startup_complete_callback_function_t callback)
+{
+	libst_startup_complete_callback_function = callback;
+}
+
+void libst_invoke_startup_complete_callback(int handle)
+{
+	if (libst_startup_complete_callback_function) {
+		libst_startup_complete_callback_function(handle);
+	}
+}
+
+void libst_invoke_folder_progress_callback(libst_folder_progress_callback_function_t callback, const char *folderID, size_t folderIDSize, long long bytesDone, long long bytesTotal, double itemsPerSecond)
+{
+	if (callback) {
+		callback(folderID, folderIDSize, bytesDone, bytesTotal, itemsPerSecond);
+	}
+}
+
+void libst_invoke_config_changed_callback(libst_config_changed_callback_function_t callback, int handle)
+{
+	if (callback) {
+		callback(handle);
+	}
+}
+
+void libst_set_crash_callback(libst_crash_callback_function_t callback)
+{
+	libst_crash_callback_function = callback;
+}
+
+void libst_invoke_crash_callback(int handle, const char *msg, size_t msgSize)
+{
+	if (libst_crash_callback_function) {
+		libst_crash_callback_function(handle, msg, msgSize);
+	}
+}
+
+void libst_clear_callbacks()
+{
+	libst_logging_callback_function = NULL;
+	libst_database_repair_callback_function = NULL;
+	libst_event_callback_function = NULL;
+	libst_startup_complete_callback_function = NULL;
+	libst_crash_callback_function = NULL;
+}
@@ -0,0 +1,617 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package main provides C bindings to embed Syncthing into native
+// applications. It is meant to be built with -buildmode=c-archive or
+// -buildmode=c-shared.
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"os"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/syncthing/syncthing/lib/api"
+	"github.com/syncthing/syncthing/lib/build"
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/logger"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/syncthing"
+)
+
+// #cgo CFLAGS: -fPIC
+// #include "c_bindings.h"
+import "C"
+
+const (
+	tlsDefaultCommonName = "syncthing"
+	logMessageEllipsis   = "…"
+	// defaultStopTimeout is the timeout libst_stop_syncthing stops an
+	// instance with.
+	defaultStopTimeout = time.Minute
+
+	// exitForced is returned by libst_stop_syncthing_with_timeout if the
+	// instance didn't stop in time and has been stopped forcefully.
+	exitForced = -1
+	// exitCancelled is returned by libst_run_syncthing if the startup has
+	// been cancelled via libst_request_shutdown.
+	exitCancelled = -2
+)
+
+var (
+	l = logger.DefaultLogger.NewFacility("main", "Main package")
+
+	// appMut guards the instances.
+	appMut sync.RWMutex
+
+	// maxLogMessageBytes limits the size of messages passed to the logging
+	// callback; zero means unlimited. Accessed atomically.
+	maxLogMessageBytes int64
+	// minLogLevel is the lowest level of the messages passed to the logging
+	// callback. Accessed atomically.
+	minLogLevel int64
+	// loggingHandlerOnce makes sure the handler forwarding log messages is
+	// only added once.
+	loggingHandlerOnce sync.Once
+)
+
+func init() {
+	l.SetFlags(0)
+	l.SetPrefix("")
+}
+
+func main() {
+	// Required for building with -buildmode=c-archive, the actual entry
+	// point is libst_run_syncthing.
+}
+
+// libst_own_device_id returns the device ID of the given instance. It is
+// known once the instance has been run.
+//
+//export libst_own_device_id
+func libst_own_device_id(handle int) *C.char {
+	return C.CString(instanceDeviceID(handle).String())
+}
+
+// libst_init_logging makes all log messages from the verbose level upwards
+// be passed to the logging callback. It is equivalent to
+// libst_init_logging_with_level with the verbose level.
+//
+//export libst_init_logging
+func libst_init_logging() {
+	libst_init_logging_with_level(int(logger.LevelVerbose))
+}
+
+// libst_init_logging_with_level makes the log messages at or above the given
+// level (see logger.LogLevel: 0 = debug, 1 = verbose, 2 = info, 3 = warning)
+// be passed to the logging callback with their level. Debug messages are only
+// logged for the facilities debugging has been enabled for, e.g. via the
+// STTRACE environment variable. Calling it again replaces the level; messages
+// are never passed more than once. Returns 2 (invalid argument) if the level
+// is out of range.
+//
+//export libst_init_logging_with_level
+func libst_init_logging_with_level(minLevel int) int {
+	if minLevel < int(logger.LevelDebug) || minLevel >= int(logger.NumLevels) {
+		return statusInvalidArgument
+	}
+	atomic.StoreInt64(&minLogLevel, int64(minLevel))
+	// Handlers can't be removed from the logger, so a single one is added
+	// which filters by the current level.
+	loggingHandlerOnce.Do(func() {
+		l.AddHandler(logger.LevelDebug, forwardLogMessage)
+	})
+	return statusOK
+}
+
+// forwardLogMessage passes the given message to the logging callback unless
+// its level is below minLogLevel.
+func forwardLogMessage(level logger.LogLevel, msg string) {
+	if int64(level) < atomic.LoadInt64(&minLogLevel) {
+		return
+	}
+	bytes := []byte(truncateLogMessage(msg, int(atomic.LoadInt64(&maxLogMessageBytes))))
+	if len(bytes) == 0 {
+		return
+	}
+	C.libst_invoke_logging_callback(C.int(level), (*C.char)(unsafe.Pointer(&bytes[0])), C.size_t(len(bytes)))
+}
+
+// libst_set_max_log_message_bytes sets the maximum number of bytes of a
+// message passed to the logging callback. Longer messages are truncated and
+// end with an ellipsis. Zero or a negative value disables the limit.
+//
+//export libst_set_max_log_message_bytes
+func libst_set_max_log_message_bytes(n int) {
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt64(&maxLogMessageBytes, int64(n))
+}
+
+// truncateLogMessage returns msg truncated to at most maxBytes bytes,
+// including the ellipsis indicating the truncation. The message is only
+// cut at rune boundaries. If the limit is too small to even hold the
+// ellipsis the message is cut without it.
+func truncateLogMessage(msg string, maxBytes int) string {
+	if maxBytes <= 0 || len(msg) <= maxBytes {
+		return msg
+	}
+	ellipsis := logMessageEllipsis
+	if maxBytes <= len(ellipsis) {
+		ellipsis = ""
+	}
+	end := maxBytes - len(ellipsis)
+	for end > 0 && !utf8.RuneStart(msg[end]) {
+		end--
+	}
+	return msg[:end] + ellipsis
+}
+
+// invokeDatabaseRepairCallback forwards the stages of a database repair to
+// the native side.
+func invokeDatabaseRepairCallback(stage backend.RepairStage, location string) {
+	l.Infof("Database repair %v: %s", stage, location)
+	bytes := []byte(location)
+	if len(bytes) == 0 {
+		C.libst_invoke_database_repair_callback(C.int(stage), nil, 0)
+		return
+	}
+	C.libst_invoke_database_repair_callback(C.int(stage), (*C.char)(unsafe.Pointer(&bytes[0])), C.size_t(len(bytes)))
+}
+
+// libst_run_syncthing runs the Syncthing instance with the given handle (0 or
+// one returned by libst_create_instance) and blocks until it has been
+// stopped. It returns the exit status of the app. When starting up fails it
+// returns 1 if the certificate couldn't be loaded or generated, 2 if the
+// config couldn't be loaded, 3 if the config directory couldn't be set or
+// created and 4 if the database couldn't be opened. Such errors, as well as
+// errors making Syncthing exit, are also passed to the logging callback as
+// fatal errors (see c_bindings.h) and their message is available via
+// libst_last_error. It returns 0 right away if the instance is running
+// already, -1 if the handle is unknown and -2 if the startup has been
+// cancelled via libst_request_shutdown. If starting up or one of the main
+// services panics, the panic is passed to the crash callback (see
+// c_bindings.h), the instance is stopped and 1 is returned.
+//
+// The config directory only applies to the given instance; the instance 0
+// uses the default config directory if none is given, other instances
+// require one. The GUI address and API key, if not empty, override the
+// configured ones for the given instance only; they are not persisted.
+//
+// The GUI assets and the profiler address are taken from the STGUIASSETS and
+// STPROFILER environment variables; use libst_run_syncthing_ex to pass them
+// explicitly.
+//
+//export libst_run_syncthing
+func libst_run_syncthing(handle int, configDir string, guiAddress string, guiApiKey string, verbose bool, allowNewerConfig bool, noDefaultConfig bool, ensureConfigDirExists bool) int {
+	return libst_run_syncthing_ex(handle, configDir, guiAddress, guiApiKey, "", "", verbose, allowNewerConfig, noDefaultConfig, ensureConfigDirExists)
+}
+
+// libst_run_syncthing_ex is like libst_run_syncthing but additionally takes
+// the directory to serve the GUI assets from instead of the built-in ones and
+// the address to serve the Go profiler on (e.g. "127.0.0.1:9090"), so the
+// environment doesn't need to be modified. Either falls back to the
+// STGUIASSETS or STPROFILER environment variable if empty.
+//
+//export libst_run_syncthing_ex
+func libst_run_syncthing_ex(handle int, configDir string, guiAddress string, guiApiKey string, assetDir string, profilerURL string, verbose bool, allowNewerConfig bool, noDefaultConfig bool, ensureConfigDirExists bool) int {
+	locs := instanceLocations(handle)
+	if locs == nil {
+		return -1
+	}
+	inst, prevReleased := activateInstance(handle)
+	if inst == nil {
+		return 0
+	}
+	// Wait until the previous run has released the database, in case its app
+	// has been stopped forcefully.
+	if prevReleased != nil {
+		<-prevReleased
+	}
+	setLastError(handle, "")
+	// background tracks what keeps running after libst_run_syncthing has
+	// returned, e.g. a startup step which has been cancelled or the shutdown
+	// of an app which has been stopped forcefully. The event logger may only
+	// be stopped once it has finished as it blocks on the stopped logger
+	// otherwise.
+	var background sync.WaitGroup
+	openLogFile()
+	defer closeLogFile()
+	cancelled := make(chan struct{})
+	appMut.Lock()
+	inst.cancelled = cancelled
+	appMut.Unlock()
+	defer func() {
+		appMut.Lock()
+		inst.active = false
+		inst.cancelled = nil
+		appMut.Unlock()
+	}()
+	if handle != 0 && configDir == "" {
+		return reportFatalError(handle, fatalCategoryConfigDir, false, 3, "Failed to set config directory:", errNoConfigDir)
+	}
+
+	if configDir != "" {
+		var err error
+		configDir, err = absolutePath(configDir)
+		if err != nil {
+			return reportFatalError(handle, fatalCategoryConfigDir, false, 3, "Failed to make config path absolute:", err)
+		}
+		if err := locs.SetBaseDir(locations.ConfigBaseDir, configDir); err != nil {
+			return reportFatalError(handle, fatalCategoryConfigDir, false, 3, "Failed to set config directory:", err)
+		}
+	}
+	if ensureConfigDirExists {
+		if err := ensureDir(locs.GetBaseDir(locations.ConfigBaseDir), 0700); err != nil {
+			return reportFatalError(handle, fatalCategoryConfigDir, false, 3, "Failure on home directory:", err)
+		}
+	}
+
+	// Print our version information up front, so any crash that happens
+	// early etc. will have it available.
+	l.Infoln(build.LongVersion)
+
+	appMut.RLock()
+	commonName, keyType := inst.certCommonName, inst.certKeyType
+	appMut.RUnlock()
+	if commonName == "" {
+		commonName = tlsDefaultCommonName
+	}
+	var cert tls.Certificate
+	var err error
+	if !cancellable(&background, cancelled, func() {
+		cert, err = syncthing.LoadOrGenerateCertificate(
+			locs.Get(locations.CertFile),
+			locs.Get(locations.KeyFile),
+			commonName,
+			keyType,
+		)
+	}, nil) {
+		return startupCancelled(handle)
+	}
+	if err != nil {
+		return reportFatalError(handle, fatalCategoryCertificate, false, 1, "Failed to load/generate certificate:", err)
+	}
+	myID := protocol.NewDeviceID(cert.Certificate[0])
+	appMut.Lock()
+	inst.myID = myID
+	appMut.Unlock()
+
+	released := make(chan struct{})
+	appMut.Lock()
+	inst.released = released
+	appMut.Unlock()
+	evLogger := events.NewLogger()
+	go evLogger.Serve()
+	defer func() {
+		go func() {
+			background.Wait()
+			evLogger.Stop()
+			close(released)
+		}()
+	}()
+
+	var cfg config.Wrapper
+	if !cancellable(&background, cancelled, func() {
+		cfg, err = syncthing.LoadConfigAtStartupWithLocations(locs, cert, evLogger, allowNewerConfig, noDefaultConfig)
+	}, nil) {
+		return startupCancelled(handle)
+	}
+	if err != nil {
+		return reportFatalError(handle, fatalCategoryConfig, false, 2, "Failed to initialize config:", err)
+	}
+	// The GUI address and API key are overridden only for this instance
+	// rather than via the environment, which would affect all instances.
+	cfg.SetGUIOverrides(guiAddress, guiApiKey)
+
+	dbLocation := locs.Get(locations.Database)
+	var ldb backend.Backend
+	closeDB := func() {
+		if ldb != nil {
+			ldb.Close()
+		}
+	}
+	if !cancellable(&background, cancelled, func() {
+		ldb, err = syncthing.OpenDBBackendWithRepairHandler(dbLocation, cfg.Options().DatabaseTuning, invokeDatabaseRepairCallback)
+	}, closeDB) {
+		return startupCancelled(handle)
+	}
+	if err != nil {
+		// The database may be locked by an instance which is about to exit.
+		return reportFatalError(handle, fatalCategoryDatabase, true, 4, "Error opening database:", err)
+	}
+	if !cancellable(&background, cancelled, func() {
+		performScheduledMaintenance(ldb, dbLocation)
+	}, closeDB) {
+		return startupCancelled(handle)
+	}
+
+	if assetDir == "" {
+		assetDir = os.Getenv("STGUIASSETS")
+	}
+	if profilerURL == "" {
+		profilerURL = os.Getenv("STPROFILER")
+	}
+	// crashed is set once the app has panicked. Accessed atomically.
+	var crashed int32
+	appOpts := syncthing.Options{
+		AssetDir:    assetDir,
+		Locations:   locs,
+		NoUpgrade:   true,
+		ProfilerURL: profilerURL,
+		Verbose:     verbose,
+		OnPanic: func(recovered interface{}, stack []byte) {
+			atomic.StoreInt32(&crashed, 1)
+			reportCrash(handle, recovered, stack)
+		},
+	}
+	// Buffer events like the REST API does so they can be queried via
+	// libst_get_events_since_json.
+	eventSub := events.NewBufferedSubscription(evLogger.Subscribe(api.DefaultEventMask), api.EventSubBufferSize)
+	nodeState := newNodeStateTracker(evLogger)
+	changes := newChangeLog(evLogger)
+
+	app := syncthing.New(cfg, ldb, evLogger, cert, appOpts)
+	appMut.Lock()
+	inst.app, inst.cfg, inst.db, inst.evLogger, inst.eventSub, inst.nodeState, inst.changeLog = app, cfg, ldb, evLogger, eventSub, nodeState, changes
+	inst.allowNewerConfig = allowNewerConfig
+	appMut.Unlock()
+
+	// Start Syncthing and block until it has finished.
+	var status syncthing.ExitStatus
+	var exitCode int
+	starting := false
+	startDone := make(chan struct{})
+	go func() {
+		select {
+		case <-cancelled:
+			// Makes the startup abort at the next step.
+			app.Stop(syncthing.ExitSuccess)
+		case <-startDone:
+		}
+	}()
+	finishedStarting := cancellable(&background, cancelled, func() {
+		starting = true
+		err = app.Start()
+	}, func() {
+		if !starting {
+			closeDB()
+			return
+		}
+		if err == nil {
+			app.Stop(syncthing.ExitSuccess)
+		}
+		<-app.Released()
+	})
+	close(startDone)
+	if !finishedStarting {
+		exitCode = startupCancelled(handle)
+	} else if err != nil && atomic.LoadInt32(&crashed) != 0 {
+		status = syncthing.ExitError
+		exitCode = status.AsInt()
+	} else if err != nil {
+		status = syncthing.ExitError
+		exitCode = reportFatalError(handle, fatalCategoryStartup, true, status.AsInt(), "Failed to start Syncthing:", err)
+	} else {
+		// Start returns once the startup is complete, including the GUI
+		// listener, right after the StartupComplete event has been emitted.
+		C.libst_invoke_startup_complete_callback(C.int(handle))
+		status = waitForApp(app, cancelled)
+		background.Add(1)
+		go func() {
+			<-app.Released()
+			background.Done()
+		}()
+		if status == syncthing.ExitError && atomic.LoadInt32(&crashed) == 0 {
+			if err := app.Error(); err != nil {
+				reportFatalError(handle, fatalCategoryRuntime, true, status.AsInt(), "Syncthing exited with error:", err)
+			}
+		}
+		exitCode = status.AsInt()
+	}
+
+	appMut.Lock()
+	inst.app, inst.cfg, inst.db, inst.evLogger, inst.eventSub, inst.nodeState, inst.changeLog = nil, nil, nil, nil, nil, nil, nil
+	appMut.Unlock()
+	stopEtaSamplers(handle)
+	stopScanWatchers(handle)
+	stopLowSpaceMonitor(handle)
+	stopConnectionErrorWatcher(handle)
+	stopEventForwarder(handle)
+	stopFolderProgress(handle)
+	resetConnectionStats(handle)
+	stopConfigWatcher(handle)
+	return exitCode
+}
+
+// libst_request_shutdown makes the given instance stop, like
+// libst_stop_syncthing, but can be called at any time while
+// libst_run_syncthing runs, including during startup, and returns
+// immediately. If the instance is still starting up, e.g. because opening
+// the database or loading the config hangs, libst_run_syncthing returns -2
+// (cancelled) right away. The startup of Syncthing itself is aborted before
+// its next step; other steps which are in progress, like opening the
+// database, are finished in the background and what they opened is closed
+// again. Running the instance again waits for that. Returns 1 if
+// libst_run_syncthing isn't running for the instance.
+//
+//export libst_request_shutdown
+func libst_request_shutdown(handle int) int {
+	appMut.Lock()
+	defer appMut.Unlock()
+	inst, ok := instances[handle]
+	if !ok || inst.cancelled == nil {
+		return statusNotRunning
+	}
+	select {
+	case <-inst.cancelled:
+	default:
+		close(inst.cancelled)
+	}
+	return statusOK
+}
+
+// cancellable runs f and returns true once it has returned. It returns false
+// as soon as cancelled is closed instead; cleanup, if not nil, is then called
+// once f has returned, or right away if f hasn't been run as cancelled has
+// been closed before. A call of f and cleanup which continues after returning
+// is tracked by background.
+func cancellable(background *sync.WaitGroup, cancelled <-chan struct{}, f func(), cleanup func()) bool {
+	select {
+	case <-cancelled:
+		if cleanup != nil {
+			cleanup()
+		}
+		return false
+	default:
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+		return true
+	case <-cancelled:
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			<-done
+			if cleanup != nil {
+				cleanup()
+			}
+		}()
+		return false
+	}
+}
+
+// startupCancelled logs that the startup of the given instance has been
+// cancelled via libst_request_shutdown and returns the according exit code.
+func startupCancelled(handle int) int {
+	l.Infoln("Startup cancelled")
+	setLastError(handle, "Startup cancelled")
+	return exitCancelled
+}
+
+// libst_stop_syncthing stops the given instance like
+// libst_stop_syncthing_with_timeout with a timeout of one minute. The exit
+// status is returned, 0 if the instance isn't running.
+//
+//export libst_stop_syncthing
+func libst_stop_syncthing(handle int) int {
+	return libst_stop_syncthing_with_timeout(handle, int(defaultStopTimeout/time.Millisecond))
+}
+
+// libst_stop_syncthing_with_timeout stops the given instance and waits at
+// most timeoutMs milliseconds for it to shut down. If it doesn't stop in
+// time, e.g. because a folder is stuck scanning, the instance is stopped
+// forcefully: libst_run_syncthing returns with exit status 1 right away while
+// the shutdown continues in the background, and -1 is returned. The database
+// remains locked until the shutdown completes, so running the instance again
+// blocks until then. Otherwise the exit status is returned, 0 if the instance
+// isn't running.
+//
+//export libst_stop_syncthing_with_timeout
+func libst_stop_syncthing_with_timeout(handle int, timeoutMs int) int {
+	if timeoutMs < 0 {
+		return -statusInvalidArgument
+	}
+	app, _ := runningApp(handle)
+	if app == nil {
+		return 0
+	}
+	stopped := make(chan syncthing.ExitStatus, 1)
+	go func() {
+		stopped <- app.Stop(syncthing.ExitSuccess)
+	}()
+	timer := time.NewTimer(time.Duration(timeoutMs) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case status := <-stopped:
+		return status.AsInt()
+	case <-timer.C:
+	}
+	l.Warnf("Syncthing didn't stop within %v, stopping it forcefully", time.Duration(timeoutMs)*time.Millisecond)
+	app.ForceStop()
+	return exitForced
+}
+
+// waitForApp blocks until the given app has stopped and returns the exit
+// status. The app is stopped when cancelled is closed.
+func waitForApp(app *syncthing.App, cancelled <-chan struct{}) syncthing.ExitStatus {
+	stopped := make(chan syncthing.ExitStatus, 1)
+	go func() {
+		stopped <- app.Wait()
+	}()
+	for {
+		select {
+		case status := <-stopped:
+			return status
+		case <-cancelled:
+			go app.Stop(syncthing.ExitSuccess)
+			cancelled = nil
+		}
+	}
+}
+
+// libst_reset_database removes the database of the given instance. It
+// should only be called while the instance isn't running.
+//
+//export libst_reset_database
+func libst_reset_database(handle int) {
+	if locs := instanceLocations(handle); locs != nil {
+		os.RemoveAll(locs.Get(locations.Database))
+	}
+}
+
+// libst_dump_goroutines returns the stack traces of all current goroutines
+// as text, in the same format as /debug/pprof/goroutine?debug=2 of the
+// profiler. It works without Syncthing running or the profiler being
+// enabled. The returned string must be freed by the caller.
+//
+//export libst_dump_goroutines
+func libst_dump_goroutines() *C.char {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		return C.CString(err.Error())
+	}
+	return C.CString(buf.String())
+}
+
+func ensureDir(dir string, mode fs.FileMode) error {
+	fs := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+	err := fs.MkdirAll(".", mode)
+	if err != nil {
+		return err
+	}
+
+	if fi, err := fs.Stat("."); err == nil {
+		// Apprently the stat may fail even though the mkdirall passed. If it
+		// does, we'll just assume things are in order and let other things
+		// fail (like loading or creating the config...).
+		currentMode := fi.Mode() & 0777
+		if currentMode != mode {
+			err := fs.Chmod(".", mode)
+			// This can fail on crappy filesystems, nothing we can do about it.
+			if err != nil {
+				l.Warnln(err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Declarations of the C helper functions used by the Go code to invoke the
+// callbacks registered by the native host application. The exported Go
+// functions (libst_*) are declared in the header generated by cgo.
+
+#ifndef LIBST_C_BINDINGS_H
+#define LIBST_C_BINDINGS_H
+
+#include <stdbool.h>
+#include <stdlib.h>
+
+// Unless documented otherwise, the functions returning an int status return
+// one of the following codes (see the status constants in utils.go):
+//   0: success
+//   1: Syncthing is not running (or the handle is unknown)
+//   2: an argument is invalid
+//   3: the folder or device doesn't exist
+//   4: the operation failed, e.g. the config couldn't be saved
+//   5: the operation can't be performed right now, e.g. as a resource is in use
+//   6: the folder or device exists already
+//   7: the operation timed out
+// Functions returning JSON return an object with an "error" key on failure.
+// Returned strings must be freed by the caller.
+
+// Instances: libst_run_syncthing blocks until Syncthing exits, so it can't
+// return the handle of the instance it runs. Instead, further instances are
+// created beforehand via libst_create_instance, which returns the handle passed
+// to libst_run_syncthing and all other functions, and removed again via
+// libst_destroy_instance once they aren't running anymore. The instance with
+// the handle 0 always exists.
+
+// The arrays returned by libst_get_devices_json and libst_get_folders_json
+// contain objects with the following keys:
+//   devices: "deviceID" (string), "name" (string), "paused" (bool),
+//            "addresses" (array of strings, "dynamic" for discovery)
+//   folders: "id" (string), "label" (string), "path" (string),
+//            "type" (string: "sendreceive", "sendonly" or "receiveonly"),
+//            "paused" (bool)
+
+// The array returned by libst_get_pending_devices_json contains objects with
+// the keys "deviceID" (string), "name" (string), "address" (string) and
+// "time" (string, RFC 3339 time of the last connection attempt).
+
+// logging: invoked for every log message with its level (see logger.LogLevel);
+// fatal errors preventing Syncthing from starting or making it exit are
+// additionally passed with level 4 and a JSON object as message with the keys
+// "category" (certificate, config, configDir, database, permission, startup
+// or runtime), "message", "exitCode" and "restartAdvisable"
+typedef void (*libst_logging_callback_function_t)(int logLevel, const char *msg, size_t msgSize);
+extern void libst_set_logging_callback(libst_logging_callback_function_t callback);
+extern void libst_invoke_logging_callback(int logLevel, const char *msg, size_t msgSize);
+
+// database repair: invoked with the stage of a repair of a corrupted database
+// (0 = started, 1 = reinitializing, 2 = finished, 3 = failed); there is no
+// progress in between as the underlying recovery doesn't report any, so
+// show an indeterminate indicator from "started" until "finished" or
+// "failed", which may take a long time on large databases
+typedef void (*libst_database_repair_callback_function_t)(int stage, const char *location, size_t locationSize);
+extern void libst_set_database_repair_callback(libst_database_repair_callback_function_t callback);
+extern void libst_invoke_database_repair_callback(int stage, const char *location, size_t locationSize);
+
+// folder ETA: invoked periodically while a folder is syncing with the bytes
+// done, the total bytes of the folder and the current transfer rate; the
+// callback is passed to libst_set_folder_eta_callback
+typedef void (*libst_folder_eta_callback_function_t)(const char *folderID, size_t folderIDSize, long long bytesDone, long long bytesTotal, double bytesPerSecond);
+extern void libst_invoke_folder_eta_callback(libst_folder_eta_callback_function_t callback, const char *folderID, size_t folderIDSize, long long bytesDone, long long bytesTotal, double bytesPerSecond);
+
+// node state: invoked when the aggregated state of all folders changes
+// (0 = starting, 1 = idle, 2 = syncing, 3 = error); the callback is passed to
+// libst_set_node_state_callback
+typedef void (*libst_node_state_callback_function_t)(int state);
+extern void libst_invoke_node_state_callback(libst_node_state_callback_function_t callback, int state);
+
+// scan completed: invoked when a scan of a folder finishes with the number of
+// items found to be added, modified and deleted; the callback is passed to
+// libst_set_scan_completed_callback
+typedef void (*libst_scan_completed_callback_function_t)(const char *folderID, size_t folderIDSize, int added, int modified, int deleted);
+extern void libst_invoke_scan_completed_callback(libst_scan_completed_callback_function_t callback, const char *folderID, size_t folderIDSize, int added, int modified, int deleted);
+
+// low space: invoked when the free space available to a folder drops below
+// its minimum (low = true) or recovers (low = false) with the free bytes; the
+// callback is passed to libst_set_low_space_callback
+typedef void (*libst_low_space_callback_function_t)(const char *folderID, size_t folderIDSize, bool low, long long freeBytes);
+extern void libst_invoke_low_space_callback(libst_low_space_callback_function_t callback, const char *folderID, size_t folderIDSize, bool low, long long freeBytes);
+
+// connection error: invoked when a connection to or from a device can't be
+// established with the device ID (empty if unknown), the address, the class of
+// the error (e.g. "refused" or "device-id-mismatch") and the error message;
+// the callback is passed to libst_set_connection_error_callback
+typedef void (*libst_connection_error_callback_function_t)(const char *deviceID, size_t deviceIDSize, const char *address, size_t addressSize, const char *errorClass, size_t errorClassSize, const char *msg, size_t msgSize);
+extern void libst_invoke_connection_error_callback(libst_connection_error_callback_function_t callback, const char *deviceID, size_t deviceIDSize, const char *address, size_t addressSize, const char *errorClass, size_t errorClassSize, const char *msg, size_t msgSize);
+
+// events: invoked for the events of the types subscribed via
+// libst_subscribe_events with the handle of the instance, the event type (see
+// libst_list_event_types_json) and the event's data as JSON
+typedef void (*libst_event_callback_function_t)(int handle, int eventType, const char *jsonData, size_t length);
+extern void libst_set_event_callback(libst_event_callback_function_t callback);
+extern void libst_invoke_event_callback(int handle, int eventType, const char *jsonData, size_t length);
+
+// startup complete: invoked with the handle of the instance once it has
+// started up, e.g. the GUI/REST API accepts connections; it is invoked once
+// per run of libst_run_syncthing and not at all if starting up fails
+typedef void (*libst_startup_complete_callback_function_t)(int handle);
+extern void libst_set_startup_complete_callback(libst_startup_complete_callback_function_t callback);
+extern void libst_invoke_startup_complete_callback(int handle);
+
+// folder progress: invoked periodically while a folder is hashing files as
+// part of a scan with the bytes hashed so far, the total bytes to hash and the
+// items committed to the index per second; the callback is passed to
+// libst_subscribe_folder_progress
+typedef void (*libst_folder_progress_callback_function_t)(const char *folderID, size_t folderIDSize, long long bytesDone, long long bytesTotal, double itemsPerSecond);
+extern void libst_invoke_folder_progress_callback(libst_folder_progress_callback_function_t callback, const char *folderID, size_t folderIDSize, long long bytesDone, long long bytesTotal, double itemsPerSecond);
+
+// config changed: invoked with the handle of the instance when its config
+// file has been changed externally; the callback is passed to
+// libst_subscribe_config_changed
+typedef void (*libst_config_changed_callback_function_t)(int handle);
+extern void libst_invoke_config_changed_callback(libst_config_changed_callback_function_t callback, int handle);
+
+// crash: invoked with the handle of the instance and a message containing the
+// stack trace when starting up or the main loop of one of its services (e.g.
+// the model or the connection service) panics; the instance is stopped as if
+// it had failed, libst_run_syncthing returns 1 and the process should be
+// restarted; panics in other goroutines of the services can't be recovered
+// and still crash the process
+typedef void (*libst_crash_callback_function_t)(int handle, const char *msg, size_t msgSize);
+extern void libst_set_crash_callback(libst_crash_callback_function_t callback);
+extern void libst_invoke_crash_callback(int handle, const char *msg, size_t msgSize);
+
+// resets all callbacks registered via the setters declared above
+extern void libst_clear_callbacks();
+
+#endif // LIBST_C_BINDINGS_H
@@ -0,0 +1,73 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTruncateLogMessage(t *testing.T) {
+	cases := []struct {
+		msg      string
+		maxBytes int
+		expected string
+	}{
+		{"short", 0, "short"},
+		{"short", 5, "short"},
+		{"a longer message", 10, "a longe…"},
+		{"äöü", 5, "ä…"},
+		{"äöü", 6, "äöü"},
+		{"äöü", 4, "…"},
+		{"äöüß", 7, "äö…"},
+		{"abc", 2, "ab"},
+		{"äbc", 1, ""},
+	}
+	for _, tc := range cases {
+		if res := truncateLogMessage(tc.msg, tc.maxBytes); res != tc.expected {
+			t.Errorf("truncateLogMessage(%q, %d) = %q, expected %q", tc.msg, tc.maxBytes, res, tc.expected)
+		}
+		if tc.maxBytes > 0 && len(truncateLogMessage(tc.msg, tc.maxBytes)) > tc.maxBytes {
+			t.Errorf("truncateLogMessage(%q, %d) exceeds limit", tc.msg, tc.maxBytes)
+		}
+	}
+}
+
+func TestCancellable(t *testing.T) {
+	var background sync.WaitGroup
+	cancelled := make(chan struct{})
+	proceed := make(chan struct{})
+	finished, cleanedUp := false, false
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(cancelled)
+	}()
+	if cancellable(&background, cancelled, func() {
+		<-proceed
+		finished = true
+	}, func() {
+		cleanedUp = true
+	}) {
+		t.Fatal("cancellable returned true although cancelled")
+	}
+
+	// The step continues in the background and is cleaned up once it has
+	// finished.
+	close(proceed)
+	background.Wait()
+	if !finished || !cleanedUp {
+		t.Errorf("Got finished %v and cleaned up %v after waiting, expected both", finished, cleanedUp)
+	}
+
+	// Nothing is run once cancelled.
+	if cancellable(&background, cancelled, func() {
+		t.Error("Cancelled step has been run")
+	}, nil) {
+		t.Error("cancellable returned true although cancelled")
+	}
+}
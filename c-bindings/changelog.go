@@ -0,0 +1,172 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// defaultChangeLogRetention is the number of changes kept per folder unless
+// configured otherwise via libst_set_folder_change_log_retention.
+const defaultChangeLogRetention = 100
+
+// A changeLogEntry is a change of an item within a folder.
+type changeLogEntry struct {
+	Time       time.Time `json:"time"`
+	Origin     string    `json:"origin"` // "local" or "remote"
+	Action     string    `json:"action"` // "added", "modified" or "deleted"
+	Type       string    `json:"type"`   // "file", "dir" or "symlink"
+	Path       string    `json:"path"`
+	ModifiedBy string    `json:"modifiedBy"`
+}
+
+// A changeLog keeps the recent changes of the folders of an instance as
+// reported by LocalChangeDetected and RemoteChangeDetected events.
+type changeLog struct {
+	mut       sync.Mutex
+	retention map[string]int              // folder ID -> entries, absent means the default
+	entries   map[string][]changeLogEntry // folder ID -> changes, oldest first
+}
+
+// newChangeLog returns a change log for the instance using the given event
+// logger. It is supposed to be created before the instance is started and
+// stops by itself when the event logger stops.
+func newChangeLog(evLogger events.Logger) *changeLog {
+	c := &changeLog{
+		retention: make(map[string]int),
+		entries:   make(map[string][]changeLogEntry),
+	}
+	sub := evLogger.Subscribe(events.LocalChangeDetected | events.RemoteChangeDetected)
+	go c.serve(sub)
+	return c
+}
+
+// libst_set_folder_change_log_retention sets how many of the recent changes
+// of the given folder are kept for libst_get_folder_change_log_json; 100 by
+// default. Older changes are dropped right away if the number is reduced.
+// Zero disables tracking the changes of the folder entirely and drops those
+// tracked so far; negative values are invalid. The setting is kept in memory
+// only and reset when the instance is restarted.
+//
+//export libst_set_folder_change_log_retention
+func libst_set_folder_change_log_retention(handle int, folderID string, entries int) int {
+	if entries < 0 {
+		return statusInvalidArgument
+	}
+	if _, cfg := runningApp(handle); cfg == nil {
+		return statusNotRunning
+	} else if _, ok := cfg.Folder(folderID); !ok {
+		return statusNotFound
+	}
+	c := runningChangeLog(handle)
+	if c == nil {
+		return statusNotRunning
+	}
+	c.setRetention(folderID, entries)
+	return statusOK
+}
+
+// libst_get_folder_change_log_json returns a JSON array with the recent
+// changes of the given folder, newest first, as far as they are kept (see
+// libst_set_folder_change_log_retention). Each change has the keys "time",
+// "origin" ("local" for changes detected by scanning, "remote" for changes
+// pulled from other devices), "action" ("added", "modified" or "deleted"),
+// "type" ("file", "dir" or "symlink"), "path" and "modifiedBy" (the short ID
+// of the device which made the change). Only changes since the instance was
+// started are contained.
+//
+//export libst_get_folder_change_log_json
+func libst_get_folder_change_log_json(handle int, folderID string) *C.char {
+	if _, cfg := runningApp(handle); cfg == nil {
+		return jsonError(errNotRunning)
+	} else if _, ok := cfg.Folder(folderID); !ok {
+		return jsonError(errNoSuchFolder)
+	}
+	c := runningChangeLog(handle)
+	if c == nil {
+		return jsonError(errNotRunning)
+	}
+	return jsonString(c.recent(folderID))
+}
+
+func (c *changeLog) serve(sub events.Subscription) {
+	// The channel is closed when the event logger stops.
+	for ev := range sub.C() {
+		data, ok := ev.Data.(map[string]string)
+		if !ok {
+			continue
+		}
+		origin := "local"
+		if ev.Type == events.RemoteChangeDetected {
+			origin = "remote"
+		}
+		c.add(data["folder"], changeLogEntry{
+			Time:       ev.Time,
+			Origin:     origin,
+			Action:     data["action"],
+			Type:       data["type"],
+			Path:       data["path"],
+			ModifiedBy: data["modifiedBy"],
+		})
+	}
+}
+
+func (c *changeLog) add(folder string, entry changeLogEntry) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	retention := c.retentionLocked(folder)
+	if retention == 0 {
+		return
+	}
+	entries := append(c.entries[folder], entry)
+	if len(entries) > retention {
+		// Move rather than reslice so the backing array doesn't grow.
+		n := copy(entries, entries[len(entries)-retention:])
+		entries = entries[:n]
+	}
+	c.entries[folder] = entries
+}
+
+func (c *changeLog) setRetention(folder string, retention int) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.retention[folder] = retention
+	entries := c.entries[folder]
+	switch {
+	case retention == 0:
+		delete(c.entries, folder)
+	case len(entries) > retention:
+		kept := make([]changeLogEntry, retention)
+		copy(kept, entries[len(entries)-retention:])
+		c.entries[folder] = kept
+	}
+}
+
+func (c *changeLog) retentionLocked(folder string) int {
+	if retention, ok := c.retention[folder]; ok {
+		return retention
+	}
+	return defaultChangeLogRetention
+}
+
+// recent returns the changes of the given folder, newest first.
+func (c *changeLog) recent(folder string) []changeLogEntry {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	entries := c.entries[folder]
+	res := make([]changeLogEntry, len(entries))
+	for i, entry := range entries {
+		res[len(entries)-1-i] = entry
+	}
+	return res
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+func TestChangeLogRetention(t *testing.T) {
+	evLogger := events.NewLogger()
+	go evLogger.Serve()
+	defer evLogger.Stop()
+	c := newChangeLog(evLogger)
+
+	for i := 0; i < defaultChangeLogRetention+10; i++ {
+		c.add("default", changeLogEntry{Path: fmt.Sprint(i)})
+	}
+	recent := c.recent("default")
+	if len(recent) != defaultChangeLogRetention {
+		t.Fatalf("Kept %d changes, expected %d", len(recent), defaultChangeLogRetention)
+	}
+	if newest := recent[0].Path; newest != fmt.Sprint(defaultChangeLogRetention+9) {
+		t.Errorf("Newest change is %s", newest)
+	}
+
+	c.setRetention("default", 2)
+	recent = c.recent("default")
+	if len(recent) != 2 || recent[0].Path != fmt.Sprint(defaultChangeLogRetention+9) || recent[1].Path != fmt.Sprint(defaultChangeLogRetention+8) {
+		t.Errorf("Unexpected changes after reducing the retention: %v", recent)
+	}
+	c.add("default", changeLogEntry{Path: "new"})
+	if recent = c.recent("default"); len(recent) != 2 || recent[0].Path != "new" {
+		t.Errorf("Unexpected changes after adding one: %v", recent)
+	}
+
+	c.setRetention("default", 0)
+	c.add("default", changeLogEntry{Path: "ignored"})
+	if recent = c.recent("default"); len(recent) != 0 {
+		t.Errorf("Changes tracked although disabled: %v", recent)
+	}
+
+	// Changes are tracked from the events.
+	evLogger.Log(events.RemoteChangeDetected, map[string]string{"folder": "other", "action": "added", "type": "file", "path": "foo"})
+	for i := 0; i < 100 && len(c.recent("other")) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if recent = c.recent("other"); len(recent) != 1 || recent[0].Origin != "remote" || recent[0].Path != "foo" {
+		t.Errorf("Unexpected changes from events: %v", recent)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// idleCheckInterval is how often libst_wait_until_idle checks the folder
+// states in addition to when they change.
+const idleCheckInterval = time.Second
+
+// libst_folder_completion returns a JSON object with the completion of the
+// given folder on the given device, like the GUI shows it for remote
+// devices: the percentage ("completion"), the bytes and items the device
+// still needs ("needBytes", "needItems"), the deletions it still needs to
+// carry out ("needDeletes") and the total bytes of the folder
+// ("globalBytes"). An empty device ID refers to the local device. The
+// completion is less than 100 while deletions are needed. Returns an error if
+// the folder or device is unknown or the folder isn't shared with the device.
+//
+//export libst_folder_completion
+func libst_folder_completion(handle int, folderID string, deviceID string) *C.char {
+	m, err := runningModel(handle)
+	if err != nil {
+		return jsonError(err)
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return jsonError(errNotRunning)
+	}
+	folder, ok := cfg.Folder(folderID)
+	if !ok {
+		return jsonError(errNoSuchFolder)
+	}
+	device := protocol.LocalDeviceID
+	if deviceID != "" {
+		device, err = protocol.DeviceIDFromString(deviceID)
+		if err != nil {
+			return jsonError(err)
+		}
+		if _, ok := cfg.Device(device); !ok {
+			return jsonError(errNoSuchDevice)
+		}
+		if device == instanceDeviceID(handle) {
+			device = protocol.LocalDeviceID
+		} else if !folder.SharedWith(device) {
+			return jsonError(errNotShared)
+		}
+	}
+	return jsonString(m.Completion(device, folderID).Map())
+}
+
+// libst_is_in_sync returns whether all folders of the given instance which
+// aren't paused are idle and the local device doesn't need anything, so
+// everything known from other devices has been synced. It returns false if
+// the instance isn't running.
+//
+//export libst_is_in_sync
+func libst_is_in_sync(handle int) bool {
+	m, err := runningModel(handle)
+	if err != nil {
+		return false
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return false
+	}
+	for id, folder := range cfg.Folders() {
+		if folder.Paused {
+			continue
+		}
+		if state, _, _ := m.State(id); state != "idle" {
+			return false
+		}
+		if need := m.NeedSize(id); need.TotalItems() > 0 || need.Bytes > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// libst_wait_until_idle blocks until no folder of the given instance which
+// isn't paused is scanning, waiting to scan or pulling files, or until
+// timeoutMs milliseconds have elapsed. Folders which are in the error state
+// or not running count as idle. This is meant to be used before quitting or
+// backing up the device, unlike libst_run_syncthing which only returns once
+// the instance has stopped. The folder states are checked whenever they
+// change, so the function returns shortly after the last folder has become
+// idle. Returns 0 once idle, 7 on timeout and 1 if the instance isn't running
+// or stops while waiting.
+//
+//export libst_wait_until_idle
+func libst_wait_until_idle(handle int, timeoutMs int) int {
+	if timeoutMs < 0 {
+		return statusInvalidArgument
+	}
+	// Subscribe before checking the states so no change is missed in between.
+	sub, _ := subscribeRunning(handle, events.StateChanged)
+	if sub == nil {
+		return statusNotRunning
+	}
+	defer sub.Unsubscribe()
+	timer := time.NewTimer(time.Duration(timeoutMs) * time.Millisecond)
+	defer timer.Stop()
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	for {
+		idle, err := foldersIdle(handle)
+		if err != nil {
+			return statusNotRunning
+		}
+		if idle {
+			return statusOK
+		}
+		select {
+		case _, ok := <-sub.C():
+			if !ok {
+				return statusNotRunning
+			}
+		case <-ticker.C:
+		case <-timer.C:
+			return statusTimeout
+		}
+	}
+}
+
+// foldersIdle returns whether all folders of the given instance which aren't
+// paused are either idle, in the error state or not running.
+func foldersIdle(handle int) (bool, error) {
+	m, err := runningModel(handle)
+	if err != nil {
+		return false, err
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return false, errNotRunning
+	}
+	for id, folder := range cfg.Folders() {
+		if folder.Paused {
+			continue
+		}
+		// An empty state means the folder isn't running at all.
+		switch state, _, _ := m.State(id); state {
+		case "idle", "error", "":
+		default:
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,264 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/locations"
+)
+
+// #include <stdlib.h>
+import "C"
+
+var bcryptExpr = regexp.MustCompile(`^\$2[aby]\$\d+\$.{50,}`)
+
+// reloadMut serializes libst_reload_config.
+var reloadMut sync.Mutex
+
+// configChanges describes the effect of applying a new config.
+type configChanges struct {
+	ChangedFolders   []string `json:"changedFolders"`
+	RestartedFolders []string `json:"restartedFolders"`
+	ChangedDevices   []string `json:"changedDevices"`
+	OptionsChanged   bool     `json:"optionsChanged"`
+	GUIChanged       bool     `json:"guiChanged"`
+	RestartRequired  bool     `json:"restartRequired"`
+
+	// otherChanged is set if any other part of the config, e.g. the ignored
+	// or pending devices, changed.
+	otherChanged bool
+}
+
+func (c configChanges) empty() bool {
+	return len(c.ChangedFolders) == 0 && len(c.ChangedDevices) == 0 && !c.OptionsChanged && !c.GUIChanged && !c.otherChanged
+}
+
+// libst_set_config_json replaces the config of the running instance with the
+// given one in the JSON format also used by the REST API and persists it. The
+// folders which were added, removed or changed are listed in the returned
+// JSON object as "changedFolders" and those among them which are (re)started
+// as "restartedFolders"; only folders whose settings actually changed are
+// restarted. "changedDevices" lists the IDs of added, removed or changed
+// devices. "restartRequired" is true if Syncthing has to be restarted for
+// the new options to take effect.
+//
+// Applying a config is minimal by itself: like with the REST API, only the
+// folders listed in "restartedFolders" are restarted, so changing an option
+// unrelated to the folders doesn't restart (and rescan) any of them. If the
+// config is equivalent to the current one, e.g. after a round trip through
+// the host application, nothing is applied nor saved.
+//
+//export libst_set_config_json
+func libst_set_config_json(handle int, configJSON string) *C.char {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return jsonError(errNotRunning)
+	}
+	to, err := config.ReadJSON(strings.NewReader(configJSON), instanceDeviceID(handle))
+	if err != nil {
+		return jsonError(err)
+	}
+
+	from := cfg.RawCopy()
+	if to.GUI.Password != from.GUI.Password && to.GUI.Password != "" && !bcryptExpr.MatchString(to.GUI.Password) {
+		hash, err := bcrypt.GenerateFromPassword([]byte(to.GUI.Password), 0)
+		if err != nil {
+			return jsonError(err)
+		}
+		to.GUI.Password = string(hash)
+	}
+
+	changes := diffConfigs(from, to)
+	if changes.empty() {
+		return jsonString(changes)
+	}
+
+	waiter, err := cfg.Replace(to)
+	if err != nil {
+		l.Warnln("Replacing config:", err)
+		return jsonError(err)
+	}
+	waiter.Wait()
+	if err := cfg.Save(); err != nil {
+		l.Warnln("Saving config:", err)
+		return jsonError(err)
+	}
+	changes.RestartRequired = cfg.RequiresRestart()
+	return jsonString(changes)
+}
+
+// libst_reload_config re-reads config.xml from the config directory of the
+// given instance and applies it, like libst_set_config_json does, so changes
+// made to the file by other means take effect without restarting Syncthing
+// and dropping its connections. The file is not written back. A config file
+// version newer than the supported one is only accepted if
+// libst_run_syncthing has been called with allowNewerConfig. Returns 1 if
+// Syncthing isn't running, 2 if the file can't be parsed or the config is
+// invalid and 4 if the file can't be read. Concurrent calls are carried out
+// one after another.
+//
+//export libst_reload_config
+func libst_reload_config(handle int) int {
+	reloadMut.Lock()
+	defer reloadMut.Unlock()
+
+	inst, ok := runningInstance(handle)
+	if !ok {
+		return statusNotRunning
+	}
+
+	fd, err := os.Open(inst.locations.Get(locations.ConfigFile))
+	if err != nil {
+		l.Warnln("Reloading config:", err)
+		return statusFailed
+	}
+	to, err := readConfig(fd, inst)
+	fd.Close()
+	if err != nil {
+		l.Warnln("Reloading config:", err)
+		return statusInvalidArgument
+	}
+
+	waiter, err := inst.cfg.Replace(to)
+	if err != nil {
+		l.Warnln("Reloading config:", err)
+		return statusInvalidArgument
+	}
+	waiter.Wait()
+	return statusOK
+}
+
+// libst_validate_config checks whether the given config XML, as contained in
+// config.xml, could be loaded by the given instance, without applying it or
+// touching the config file. A config file version newer than the supported
+// one is only accepted if libst_run_syncthing has been called with
+// allowNewerConfig. Returns a JSON object with "ok" set to true, or set to
+// false and the reason in "error".
+//
+//export libst_validate_config
+func libst_validate_config(handle int, xml string) *C.char {
+	inst, ok := runningInstance(handle)
+	if !ok {
+		return validationResult(errNotRunning)
+	}
+	_, err := readConfig(strings.NewReader(xml), inst)
+	return validationResult(err)
+}
+
+func validationResult(err error) *C.char {
+	if err != nil {
+		return jsonString(map[string]interface{}{"ok": false, "error": err.Error()})
+	}
+	return jsonString(map[string]interface{}{"ok": true})
+}
+
+// readConfig parses the given config XML for the given instance, checking
+// the version like syncthing.LoadConfigAtStartup does.
+func readConfig(r io.Reader, inst instance) (config.Configuration, error) {
+	cfg, err := config.ReadXML(r, inst.myID)
+	if err == io.EOF {
+		return cfg, errors.New("unexpected end of file, truncated or empty configuration?")
+	} else if err != nil {
+		return cfg, err
+	}
+	if cfg.OriginalVersion > config.CurrentVersion && !inst.allowNewerConfig {
+		return cfg, fmt.Errorf("config file version (%d) is newer than supported version (%d)", cfg.OriginalVersion, config.CurrentVersion)
+	}
+	return cfg, nil
+}
+
+// diffConfigs determines which parts of the config change when replacing
+// from with to, following the logic of model.CommitConfiguration for
+// deciding which folders need to be restarted.
+func diffConfigs(from, to config.Configuration) configChanges {
+	changes := configChanges{
+		ChangedFolders:   []string{},
+		RestartedFolders: []string{},
+		ChangedDevices:   []string{},
+		OptionsChanged:   !reflect.DeepEqual(from.Options, to.Options),
+		GUIChanged:       !reflect.DeepEqual(from.GUI, to.GUI) || !reflect.DeepEqual(from.LDAP, to.LDAP),
+		otherChanged:     from.Version != to.Version || !equalObservedDevices(from.IgnoredDevices, to.IgnoredDevices) || !equalObservedDevices(from.PendingDevices, to.PendingDevices),
+	}
+
+	fromFolders := make(map[string]config.FolderConfiguration, len(from.Folders))
+	for _, folder := range from.Folders {
+		fromFolders[folder.ID] = folder
+	}
+	for _, toFolder := range to.Folders {
+		fromFolder, ok := fromFolders[toFolder.ID]
+		delete(fromFolders, toFolder.ID)
+		switch {
+		case !ok:
+			changes.ChangedFolders = append(changes.ChangedFolders, toFolder.ID)
+			if !toFolder.Paused {
+				changes.RestartedFolders = append(changes.RestartedFolders, toFolder.ID)
+			}
+		case !reflect.DeepEqual(fromFolder.RequiresRestartOnly(), toFolder.RequiresRestartOnly()):
+			changes.ChangedFolders = append(changes.ChangedFolders, toFolder.ID)
+			if !fromFolder.Paused || !toFolder.Paused {
+				changes.RestartedFolders = append(changes.RestartedFolders, toFolder.ID)
+			}
+		case !equalFolders(fromFolder, toFolder):
+			changes.ChangedFolders = append(changes.ChangedFolders, toFolder.ID)
+		}
+	}
+	for id := range fromFolders {
+		changes.ChangedFolders = append(changes.ChangedFolders, id)
+	}
+
+	fromDevices := from.DeviceMap()
+	for id, toDevice := range to.DeviceMap() {
+		fromDevice, ok := fromDevices[id]
+		delete(fromDevices, id)
+		if !ok || !reflect.DeepEqual(fromDevice, toDevice) {
+			changes.ChangedDevices = append(changes.ChangedDevices, id.String())
+		}
+	}
+	for id := range fromDevices {
+		changes.ChangedDevices = append(changes.ChangedDevices, id.String())
+	}
+
+	sort.Strings(changes.ChangedFolders)
+	sort.Strings(changes.RestartedFolders)
+	sort.Strings(changes.ChangedDevices)
+	return changes
+}
+
+// equalFolders returns whether the exported settings of both folders are
+// equal, including those not requiring a restart.
+func equalFolders(a, b config.FolderConfiguration) bool {
+	return equalJSON(a, b)
+}
+
+// equalObservedDevices returns whether both lists contain the same devices,
+// treating a nil list like an empty one.
+func equalObservedDevices(a, b []config.ObservedDevice) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	return equalJSON(a, b)
+}
+
+func equalJSON(a, b interface{}) bool {
+	as, errA := json.Marshal(a)
+	bs, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(as, bs)
+}
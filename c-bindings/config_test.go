@@ -0,0 +1,75 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	device1, _ := protocol.DeviceIDFromString("AIR6LPZ-7K4PTTV-UXQSMUU-CPQ5YWH-OEDFIIQ-JUG777G-2YQXXR5-YD6AWQR")
+	device2, _ := protocol.DeviceIDFromString("GYRZZQB-IRNPV4Z-T7TC52W-EQYJ3TT-FDQW6MW-DFLMU42-SSSU6EM-FBK2VAY")
+
+	from := config.New(device1)
+	from.Devices = append(from.Devices, config.NewDeviceConfiguration(device2, "device2"))
+	for _, id := range []string{"unchanged", "label", "restart", "removed", "paused"} {
+		folder := config.NewFolderConfiguration(device1, id, id, fs.FilesystemTypeBasic, "/tmp/"+id)
+		folder.Paused = id == "paused"
+		from.Folders = append(from.Folders, folder)
+	}
+
+	if changes := diffConfigs(from, from.Copy()); !changes.empty() {
+		t.Errorf("unexpected changes for identical configs: %+v", changes)
+	}
+
+	to := from.Copy()
+	to.Folders[1].Label = "new label"
+	to.Folders[1].ScanProgressIntervalS = 10
+	to.Folders[2].RescanIntervalS++
+	to.Folders[4].RescanIntervalS++
+	to.Folders = append(to.Folders[:3], to.Folders[4], config.NewFolderConfiguration(device1, "added", "added", fs.FilesystemTypeBasic, "/tmp/added"))
+	to.Devices[1].Name = "renamed"
+
+	changes := diffConfigs(from, to)
+	if expected := []string{"added", "label", "paused", "removed", "restart"}; !reflect.DeepEqual(changes.ChangedFolders, expected) {
+		t.Errorf("changed folders: got %v, expected %v", changes.ChangedFolders, expected)
+	}
+	if expected := []string{"added", "restart"}; !reflect.DeepEqual(changes.RestartedFolders, expected) {
+		t.Errorf("restarted folders: got %v, expected %v", changes.RestartedFolders, expected)
+	}
+	if expected := []string{device2.String()}; !reflect.DeepEqual(changes.ChangedDevices, expected) {
+		t.Errorf("changed devices: got %v, expected %v", changes.ChangedDevices, expected)
+	}
+	if changes.OptionsChanged || changes.GUIChanged {
+		t.Errorf("unexpected options/GUI changes: %+v", changes)
+	}
+
+	to = from.Copy()
+	to.Options.ReconnectIntervalS++
+	changes = diffConfigs(from, to)
+	if !changes.OptionsChanged || len(changes.ChangedFolders) != 0 || len(changes.RestartedFolders) != 0 {
+		t.Errorf("unexpected changes for an unrelated option: %+v", changes)
+	}
+
+	to = from.Copy()
+	to.IgnoredDevices = append(to.IgnoredDevices, config.ObservedDevice{ID: device2, Name: "ignored"})
+	changes = diffConfigs(from, to)
+	if changes.empty() || len(changes.ChangedFolders) != 0 || len(changes.ChangedDevices) != 0 || changes.OptionsChanged || changes.GUIChanged {
+		t.Errorf("unexpected changes for an ignored device: %+v", changes)
+	}
+
+	to = from.Copy()
+	to.IgnoredDevices = []config.ObservedDevice{}
+	if changes := diffConfigs(from, to); !changes.empty() {
+		t.Errorf("unexpected changes for an empty list of ignored devices: %+v", changes)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+// #include "c_bindings.h"
+import "C"
+
+// configChangedQuietPeriod is how long the config file must not have changed
+// before its contents are compared with the loaded config.
+const configChangedQuietPeriod = 2 * time.Second
+
+// A configWatcher watches the config file of an instance and invokes a
+// callback when its contents differ from the loaded config.
+type configWatcher struct {
+	handle   int
+	cfg      config.Wrapper
+	callback C.libst_config_changed_callback_function_t
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// configFileMatcher makes the watcher of a config directory ignore
+// everything but the config file.
+type configFileMatcher string
+
+func (m configFileMatcher) ShouldIgnore(name string) bool {
+	return name != "." && name != string(m)
+}
+
+func (configFileMatcher) SkipIgnoredDirs() bool {
+	return true
+}
+
+var (
+	configWatcherMut sync.Mutex
+	configWatchers   = make(map[int]*configWatcher)
+)
+
+// libst_subscribe_config_changed registers a callback which is invoked with
+// the handle of the given instance when its config file has been changed by
+// something else than Syncthing, e.g. another process or the user editing
+// it, so its contents differ from the loaded config. The config can then be
+// reloaded via libst_reload_config. The file is watched via file system
+// notifications; the callback is invoked once the file hasn't changed for two
+// seconds and only once for the same contents. Only one callback per
+// instance is supported, it replaces any previously registered one and is
+// removed when the instance stops. Passing NULL removes the callback. Returns
+// 4 if the config directory can't be watched.
+//
+//export libst_subscribe_config_changed
+func libst_subscribe_config_changed(handle int, callback C.libst_config_changed_callback_function_t) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+
+	configWatcherMut.Lock()
+	defer configWatcherMut.Unlock()
+	if _, current := runningApp(handle); current != cfg {
+		// The instance has stopped and removed its watcher meanwhile.
+		return statusNotRunning
+	}
+	if w, ok := configWatchers[handle]; ok {
+		w.close()
+		delete(configWatchers, handle)
+	}
+	if callback == nil {
+		return statusOK
+	}
+
+	dir, name := filepath.Split(cfg.ConfigPath())
+	ctx, cancel := context.WithCancel(context.Background())
+	filesystem := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+	eventChan, errChan, err := filesystem.Watch(".", configFileMatcher(name), ctx, true)
+	if err != nil {
+		cancel()
+		l.Warnln("Watching config file:", err)
+		return statusFailed
+	}
+	w := &configWatcher{
+		handle:   handle,
+		cfg:      cfg,
+		callback: callback,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	configWatchers[handle] = w
+	go w.serve(ctx, eventChan, errChan)
+	return statusOK
+}
+
+// stopConfigWatcher removes the config changed callback of the given
+// instance.
+func stopConfigWatcher(handle int) {
+	configWatcherMut.Lock()
+	defer configWatcherMut.Unlock()
+	if w, ok := configWatchers[handle]; ok {
+		w.close()
+		delete(configWatchers, handle)
+	}
+}
+
+// close stops the watcher and waits until the callback isn't invoked
+// anymore.
+func (w *configWatcher) close() {
+	w.cancel()
+	<-w.done
+}
+
+func (w *configWatcher) serve(ctx context.Context, eventChan <-chan fs.Event, errChan <-chan error) {
+	defer close(w.done)
+
+	timer := time.NewTimer(configChangedQuietPeriod)
+	timer.Stop()
+	defer timer.Stop()
+	// reported holds the contents of the file the callback has been invoked
+	// for last, so it isn't invoked again until they change.
+	var reported []byte
+	for {
+		select {
+		case <-eventChan:
+			timer.Reset(configChangedQuietPeriod)
+
+		case <-timer.C:
+			data, changed := w.check()
+			if changed && !bytes.Equal(data, reported) {
+				reported = data
+				C.libst_invoke_config_changed_callback(w.callback, C.int(w.handle))
+			}
+
+		case err := <-errChan:
+			l.Warnln("Watching config file:", err)
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check returns the contents of the config file and whether they differ
+// from the loaded config, as they would be saved.
+func (w *configWatcher) check() ([]byte, bool) {
+	data, err := ioutil.ReadFile(w.cfg.ConfigPath())
+	if err != nil {
+		l.Debugln("Reading config file:", err)
+		return nil, false
+	}
+	loaded := w.cfg.RawCopy()
+	var buf bytes.Buffer
+	if err := loaded.WriteXML(&buf); err != nil {
+		l.Debugln("Serializing config:", err)
+		return nil, false
+	}
+	return data, !bytes.Equal(data, buf.Bytes())
+}
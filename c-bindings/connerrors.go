@@ -0,0 +1,132 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+// #include "c_bindings.h"
+import "C"
+
+// A connectionErrorWatcher invokes a callback for the ConnectionFailed
+// events of an instance.
+type connectionErrorWatcher struct {
+	callback C.libst_connection_error_callback_function_t
+	sub      events.Subscription
+	stop     chan struct{}
+}
+
+var (
+	connectionErrorMut      sync.Mutex
+	connectionErrorWatchers = make(map[int]*connectionErrorWatcher)
+)
+
+// libst_set_connection_error_callback registers a callback which is invoked
+// whenever a connection to or from another device can't be established. It
+// is passed the device ID (empty if unknown), the address, the class of the
+// error and the error message. The class is one of "tls-handshake" (e.g. the
+// other side isn't Syncthing or rejected our certificate),
+// "device-id-mismatch" (another device answered at the address, or we
+// connected to ourselves), "refused" (nothing listens at the address),
+// "timeout" (the address is unreachable, e.g. due to a firewall),
+// "relay-error" (the relay couldn't establish the connection, e.g. because
+// the device isn't connected to it), "protocol-error" (e.g. an incompatible
+// version) or "other". Failures are reported per address, so a single
+// connection attempt may invoke the callback several times. Only one
+// callback per instance is supported, it replaces any previously registered
+// one and is removed when the instance stops. Passing NULL removes the
+// callback.
+//
+//export libst_set_connection_error_callback
+func libst_set_connection_error_callback(handle int, callback C.libst_connection_error_callback_function_t) int {
+	if callback == nil {
+		if runningEventLogger(handle) == nil {
+			return statusNotRunning
+		}
+		stopConnectionErrorWatcher(handle)
+		return statusOK
+	}
+
+	// Subscribe without holding connectionErrorMut as it may take a moment.
+	sub, evLogger := subscribeRunning(handle, events.ConnectionFailed)
+	if sub == nil {
+		return statusNotRunning
+	}
+
+	connectionErrorMut.Lock()
+	defer connectionErrorMut.Unlock()
+	if runningEventLogger(handle) != evLogger {
+		// The instance has stopped and removed its watcher meanwhile.
+		sub.Unsubscribe()
+		return statusNotRunning
+	}
+	if w, ok := connectionErrorWatchers[handle]; ok {
+		close(w.stop)
+	}
+	w := &connectionErrorWatcher{
+		callback: callback,
+		sub:      sub,
+		stop:     make(chan struct{}),
+	}
+	connectionErrorWatchers[handle] = w
+	go w.serve()
+	return statusOK
+}
+
+// stopConnectionErrorWatcher stops and removes the connection error watcher
+// of the given instance.
+func stopConnectionErrorWatcher(handle int) {
+	connectionErrorMut.Lock()
+	defer connectionErrorMut.Unlock()
+	if w, ok := connectionErrorWatchers[handle]; ok {
+		close(w.stop)
+		delete(connectionErrorWatchers, handle)
+	}
+}
+
+func (w *connectionErrorWatcher) serve() {
+	defer w.sub.Unsubscribe()
+	for {
+		select {
+		case ev, ok := <-w.sub.C():
+			if !ok {
+				// The event logger has been stopped.
+				return
+			}
+			data, ok := ev.Data.(map[string]string)
+			if !ok {
+				continue
+			}
+			w.invoke(data["device"], data["address"], data["class"], data["error"])
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *connectionErrorWatcher) invoke(device, address, class, message string) {
+	deviceID, deviceIDSize := cStringView(device)
+	addr, addrSize := cStringView(address)
+	errorClass, errorClassSize := cStringView(class)
+	msg, msgSize := cStringView(message)
+	C.libst_invoke_connection_error_callback(w.callback, deviceID, deviceIDSize, addr, addrSize, errorClass, errorClassSize, msg, msgSize)
+}
+
+// cStringView returns a pointer to the bytes of the given string and its
+// size for passing it to C without copying. The bytes are not null
+// terminated and must not be retained by the C code.
+func cStringView(s string) (*C.char, C.size_t) {
+	if len(s) == 0 {
+		return nil, 0
+	}
+	bytes := []byte(s)
+	return (*C.char)(unsafe.Pointer(&bytes[0])), C.size_t(len(bytes))
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync"
+
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// connectionStats is the value of an entry of the object returned by
+// libst_connection_stats.
+type connectionStats struct {
+	InBytesTotal      int64   `json:"inBytesTotal"`
+	OutBytesTotal     int64   `json:"outBytesTotal"`
+	InBytesPerSecond  float64 `json:"inBytesPerSecond"`
+	OutBytesPerSecond float64 `json:"outBytesPerSecond"`
+}
+
+var (
+	connStatsMut sync.Mutex
+	// connStatsSamples holds the statistics of the connections of each
+	// instance as of the previous call of libst_connection_stats, to compute
+	// the rates from.
+	connStatsSamples = make(map[int]map[string]protocol.Statistics)
+)
+
+// libst_connection_stats returns a JSON object mapping the IDs of the
+// connected devices to the bytes received from ("inBytesTotal") and sent to
+// ("outBytesTotal") them via the current connection and the rates in bytes
+// per second ("inBytesPerSecond" and "outBytesPerSecond"). The rates are
+// averaged over the time since the previous call, so the function is meant
+// to be polled, e.g. once a second; they are zero on the first call and after
+// reconnecting. The object is empty if no device is connected.
+//
+//export libst_connection_stats
+func libst_connection_stats(handle int) *C.char {
+	m, err := runningModel(handle)
+	if err != nil {
+		return jsonError(err)
+	}
+	conns, _ := m.ConnectionStats()["connections"].(map[string]model.ConnectionInfo)
+
+	connStatsMut.Lock()
+	defer connStatsMut.Unlock()
+	prev := connStatsSamples[handle]
+	samples := make(map[string]protocol.Statistics, len(conns))
+	res := make(map[string]connectionStats, len(conns))
+	for id, conn := range conns {
+		if !conn.Connected {
+			continue
+		}
+		samples[id] = conn.Statistics
+		stats := connectionStats{
+			InBytesTotal:  conn.InBytesTotal,
+			OutBytesTotal: conn.OutBytesTotal,
+		}
+		// The totals are per connection, so lower ones mean the device has
+		// reconnected in between.
+		last, ok := prev[id]
+		secs := conn.At.Sub(last.At).Seconds()
+		reconnected := conn.InBytesTotal < last.InBytesTotal || conn.OutBytesTotal < last.OutBytesTotal
+		if ok && secs > 0 && !reconnected {
+			stats.InBytesPerSecond = float64(conn.InBytesTotal-last.InBytesTotal) / secs
+			stats.OutBytesPerSecond = float64(conn.OutBytesTotal-last.OutBytesTotal) / secs
+		}
+		res[id] = stats
+	}
+	connStatsSamples[handle] = samples
+	return jsonString(res)
+}
+
+// resetConnectionStats forgets the statistics sampled for the given instance.
+func resetConnectionStats(handle int) {
+	connStatsMut.Lock()
+	defer connStatsMut.Unlock()
+	delete(connStatsSamples, handle)
+}
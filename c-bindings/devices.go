@@ -0,0 +1,156 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// A deviceInfo is an entry of the array returned by libst_get_devices_json.
+type deviceInfo struct {
+	DeviceID  string   `json:"deviceID"`
+	Name      string   `json:"name"`
+	Paused    bool     `json:"paused"`
+	Addresses []string `json:"addresses"`
+}
+
+// libst_get_devices_json returns a JSON array with the devices configured
+// for the given instance, except the instance's own device, sorted by device
+// ID. See c_bindings.h for the keys of the entries. The array is empty if the
+// instance isn't running.
+//
+//export libst_get_devices_json
+func libst_get_devices_json(handle int) *C.char {
+	devices := []deviceInfo{}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return jsonString(devices)
+	}
+	myID := instanceDeviceID(handle)
+	for _, dev := range cfg.RawCopy().Devices {
+		if dev.DeviceID == myID {
+			continue
+		}
+		addrs := dev.Addresses
+		if addrs == nil {
+			addrs = []string{}
+		}
+		devices = append(devices, deviceInfo{
+			DeviceID:  dev.DeviceID.String(),
+			Name:      dev.Name,
+			Paused:    dev.Paused,
+			Addresses: addrs,
+		})
+	}
+	return jsonString(devices)
+}
+
+// libst_add_device adds the device with the given ID and name to the config
+// of the given instance, e.g. to accept an invitation. The addresses are
+// given as comma separated list of URLs like "tcp://192.0.2.1:22000"; an
+// empty list means "dynamic", so the device is looked up via discovery.
+// Returns 2 if the device ID is malformed and 6 if the device is configured
+// already, including the instance's own device. The change is persisted and
+// the device is connected to right away.
+//
+//export libst_add_device
+func libst_add_device(handle int, deviceID string, name string, addresses string) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	id, err := protocol.DeviceIDFromString(deviceID)
+	if err != nil {
+		return statusInvalidArgument
+	}
+	if _, ok := cfg.Device(id); ok {
+		return statusExists
+	}
+	dev := config.NewDeviceConfiguration(id, name)
+	var addrs []string
+	for _, addr := range strings.Split(addresses, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) > 0 {
+		dev.Addresses = addrs
+	}
+	waiter, err := cfg.SetDevice(dev)
+	if err != nil {
+		l.Warnln("Adding device:", err)
+		return statusFailed
+	}
+	waiter.Wait()
+	return saveConfig(cfg)
+}
+
+// libst_set_device_rate_limits sets the send and receive rate limits in KiB/s
+// for the given device. These apply in addition to the global limits; zero
+// means unlimited. The change is applied to existing connections and
+// persisted.
+//
+//export libst_set_device_rate_limits
+func libst_set_device_rate_limits(handle int, deviceID string, sendKbps int, recvKbps int) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	id, err := protocol.DeviceIDFromString(deviceID)
+	if err != nil || sendKbps < 0 || recvKbps < 0 {
+		return statusInvalidArgument
+	}
+	dev, ok := cfg.Device(id)
+	if !ok || id == instanceDeviceID(handle) {
+		return statusNotFound
+	}
+	dev.MaxSendKbps = sendKbps
+	dev.MaxRecvKbps = recvKbps
+	waiter, err := cfg.SetDevice(dev)
+	if err != nil {
+		l.Warnln("Setting device rate limits:", err)
+		return statusFailed
+	}
+	waiter.Wait()
+	return saveConfig(cfg)
+}
+
+// libst_set_device_paused pauses or resumes syncing with the given device.
+// While a device is paused it is disconnected and no connections to or from
+// it are established. Returns 2 if the device ID is malformed and 3 if there
+// is no such device. The change can be made as soon as the config has been
+// loaded, while Syncthing is still starting up, and is persisted.
+//
+//export libst_set_device_paused
+func libst_set_device_paused(handle int, deviceID string, paused bool) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	id, err := protocol.DeviceIDFromString(deviceID)
+	if err != nil {
+		return statusInvalidArgument
+	}
+	dev, ok := cfg.Device(id)
+	if !ok || id == instanceDeviceID(handle) {
+		return statusNotFound
+	}
+	dev.Paused = paused
+	waiter, err := cfg.SetDevice(dev)
+	if err != nil {
+		l.Warnln("Pausing device:", err)
+		return statusFailed
+	}
+	waiter.Wait()
+	return saveConfig(cfg)
+}
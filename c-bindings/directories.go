@@ -0,0 +1,114 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/rand"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// directoryStatus is the result of checking a directory via
+// libst_check_directories.
+type directoryStatus struct {
+	Path        string `json:"path"`
+	Exists      bool   `json:"exists"`
+	Writable    bool   `json:"writable"`
+	Permissions string `json:"permissions,omitempty"`
+	FreeBytes   int64  `json:"freeBytes,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// libst_check_directories checks whether the given config and data
+// directories are usable before running Syncthing with them. It returns a
+// JSON object with an entry for each non-empty path ("config" and "data")
+// containing the absolute path ("path"), whether the directory exists
+// ("exists") and is writable ("writable"), its permission bits in octal
+// ("permissions"), the free space in bytes if known ("freeBytes") and the
+// problem found, if any ("error"). Unless readOnly is true, missing
+// directories are created and their permissions are fixed like
+// libst_run_syncthing does with ensureConfigDirExists, so the result reflects
+// the state afterwards and writability is probed by creating and removing a
+// temporary file. If readOnly is true, nothing is created and the permissions
+// of the directory are checked instead.
+//
+//export libst_check_directories
+func libst_check_directories(configDir string, dataDir string, readOnly bool) *C.char {
+	res := make(map[string]directoryStatus)
+	if configDir != "" {
+		res["config"] = checkDirectory(configDir, readOnly)
+	}
+	if dataDir != "" {
+		res["data"] = checkDirectory(dataDir, readOnly)
+	}
+	return jsonString(res)
+}
+
+// checkDirectory checks the given directory, creating it via ensureDir
+// first unless readOnly is true.
+func checkDirectory(dir string, readOnly bool) directoryStatus {
+	status := directoryStatus{Path: dir}
+	dir, err := absolutePath(dir)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Path = dir
+	if !readOnly {
+		if err := ensureDir(dir, 0700); err != nil {
+			status.Error = err.Error()
+			return status
+		}
+	}
+
+	filesystem := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+	fi, err := filesystem.Stat(".")
+	if fs.IsNotExist(err) {
+		status.Error = "directory does not exist"
+		return status
+	} else if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if !fi.IsDir() {
+		status.Error = "not a directory"
+		return status
+	}
+	status.Exists = true
+	status.Permissions = fmt.Sprintf("%04o", fi.Mode()&0777)
+	if usage, err := filesystem.Usage("."); err == nil {
+		status.FreeBytes = usage.Free
+	}
+
+	if readOnly {
+		err = checkWritable(dir)
+	} else {
+		err = probeWritable(filesystem)
+	}
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Writable = true
+	return status
+}
+
+// probeWritable checks whether the root of the given filesystem is writable
+// by creating and removing a temporary file.
+func probeWritable(filesystem fs.Filesystem) error {
+	name := fs.TempName("check-" + rand.String(8))
+	fd, err := filesystem.Create(name)
+	if err != nil {
+		return err
+	}
+	fd.Close()
+	return filesystem.Remove(name)
+}
@@ -0,0 +1,44 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckDirectoryReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-check-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	status := checkDirectory(dir, true)
+	if !status.Exists || !status.Writable || status.Error != "" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if names, err := ioutil.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(names) != 0 {
+		t.Errorf("checking in read-only mode modified the directory: %v", names)
+	}
+
+	missing := filepath.Join(dir, "missing")
+	if status := checkDirectory(missing, true); status.Exists || status.Error == "" {
+		t.Errorf("unexpected status for a missing directory: %+v", status)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Error("checking in read-only mode created the directory")
+	}
+
+	if status := checkDirectory(missing, false); !status.Exists || !status.Writable {
+		t.Errorf("unexpected status after creating the directory: %+v", status)
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !windows
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// checkWritable returns an error if the given directory isn't writable by
+// the current user, without modifying it.
+func checkWritable(dir string) error {
+	return unix.Access(dir, unix.W_OK)
+}
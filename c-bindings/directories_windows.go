@@ -0,0 +1,30 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build windows
+
+package main
+
+import "golang.org/x/sys/windows"
+
+// fileAddFile is the access right to create files in a directory.
+const fileAddFile = 0x0002
+
+// checkWritable returns an error if the given directory isn't writable by
+// the current user, without modifying it. The read-only attribute doesn't
+// apply to directories, so the ACL is checked by opening the directory with
+// the right to create files in it.
+func checkWritable(dir string) error {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return err
+	}
+	h, err := windows.CreateFile(path, fileAddFile, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	return windows.CloseHandle(h)
+}
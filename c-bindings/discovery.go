@@ -0,0 +1,91 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"time"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// sourceStatus is the status of a discovery method or listener in the
+// result of libst_get_discovery_status_json.
+type sourceStatus struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// libst_set_discovery_cache_options sets for how many seconds addresses
+// found via global discovery are cached. Zero disables the caching. By
+// default they are cached for five minutes. The setting applies right away
+// to the running instance but is not persisted.
+//
+//export libst_set_discovery_cache_options
+func libst_set_discovery_cache_options(handle int, ttlSeconds int) int {
+	if ttlSeconds < 0 {
+		return statusInvalidArgument
+	}
+	app, _ := runningApp(handle)
+	if app == nil || app.Discoverer() == nil {
+		return statusNotRunning
+	}
+	app.Discoverer().SetCacheTime(time.Duration(ttlSeconds) * time.Second)
+	return statusOK
+}
+
+// libst_clear_discovery_cache removes all cached discovery results, so the
+// addresses of devices are looked up again on the next connection attempt.
+//
+//export libst_clear_discovery_cache
+func libst_clear_discovery_cache(handle int) int {
+	app, _ := runningApp(handle)
+	if app == nil || app.Discoverer() == nil {
+		return statusNotRunning
+	}
+	app.Discoverer().ClearCache()
+	return statusOK
+}
+
+// libst_get_discovery_status_json returns a JSON object with the status of
+// the discovery methods ("discovery", keyed by e.g. "global@<server URL>" or
+// "IPv4 local") and of the listeners ("listeners", keyed by the listen
+// address; relays are listeners as well). Each entry has an "ok" key which is
+// false if the method or listener has failed, in which case "error" contains
+// the last error, e.g. because the global discovery server is unreachable.
+// The object is empty if the instance isn't running.
+//
+//export libst_get_discovery_status_json
+func libst_get_discovery_status_json(handle int) *C.char {
+	app, _ := runningApp(handle)
+	if app == nil || app.Discoverer() == nil || app.ConnectionsService() == nil {
+		return jsonString(struct{}{})
+	}
+	discovery := make(map[string]sourceStatus)
+	for name, err := range app.Discoverer().ChildErrors() {
+		discovery[name] = newSourceStatus(err)
+	}
+	listeners := make(map[string]sourceStatus)
+	for addr, status := range app.ConnectionsService().ListenerStatus() {
+		if status.Error != nil {
+			listeners[addr] = sourceStatus{Error: *status.Error}
+		} else {
+			listeners[addr] = sourceStatus{OK: true}
+		}
+	}
+	return jsonString(map[string]interface{}{
+		"discovery": discovery,
+		"listeners": listeners,
+	})
+}
+
+func newSourceStatus(err error) sourceStatus {
+	if err != nil {
+		return sourceStatus{Error: err.Error()}
+	}
+	return sourceStatus{OK: true}
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// folderDiskUsage is the result of libst_get_folder_disk_usage_json. All
+// sizes are in bytes.
+type folderDiskUsage struct {
+	Folder        string    `json:"folder"`
+	DiskBytes     int64     `json:"diskBytes"`
+	IndexedBytes  int64     `json:"indexedBytes"`
+	DeltaBytes    int64     `json:"deltaBytes"`
+	TempBytes     int64     `json:"tempBytes"`
+	ConflictBytes int64     `json:"conflictBytes"`
+	VersionBytes  int64     `json:"versionBytes"`
+	Time          time.Time `json:"time"`
+	Cached        bool      `json:"cached"`
+}
+
+type diskUsageKey struct {
+	handle int
+	folder string
+}
+
+var (
+	diskUsageMut   sync.Mutex
+	diskUsageCache = make(map[diskUsageKey]folderDiskUsage)
+)
+
+// libst_get_folder_disk_usage_json returns a JSON object with the bytes the
+// given folder actually occupies on disk ("diskBytes"), the bytes of the
+// files in the local index ("indexedBytes") and the difference of both
+// ("deltaBytes"). The difference is broken down into temporary files of
+// unfinished downloads ("tempBytes"), conflict copies ("conflictBytes") and
+// old versions kept within the folder's .stversions directory
+// ("versionBytes"); versions kept elsewhere are not taken into account.
+// Files modified or added since the last scan contribute to the difference
+// as well.
+//
+// Determining the disk usage requires walking the whole folder, which can
+// take a long time on large folders or slow storage. If cached is true the
+// result of the previous walk is returned instead if there is one; "time"
+// is when the walk happened and "cached" whether it is such a previous
+// result. The indexed size is always up to date.
+//
+//export libst_get_folder_disk_usage_json
+func libst_get_folder_disk_usage_json(handle int, folderID string, cached bool) *C.char {
+	m, err := runningModel(handle)
+	if err != nil {
+		return jsonError(err)
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return jsonError(errNotRunning)
+	}
+	folder, ok := cfg.Folder(folderID)
+	if !ok {
+		return jsonError(errNoSuchFolder)
+	}
+
+	key := diskUsageKey{handle, folderID}
+	diskUsageMut.Lock()
+	usage, ok := diskUsageCache[key]
+	diskUsageMut.Unlock()
+	if cached && ok {
+		usage.Cached = true
+	} else {
+		usage, err = walkDiskUsage(folder.Filesystem())
+		if err != nil {
+			return jsonError(err)
+		}
+		diskUsageMut.Lock()
+		diskUsageCache[key] = usage
+		diskUsageMut.Unlock()
+	}
+
+	usage.Folder = folderID
+	usage.IndexedBytes = m.LocalSize(folderID).Bytes
+	usage.DeltaBytes = usage.DiskBytes - usage.IndexedBytes
+	return jsonString(usage)
+}
+
+// walkDiskUsage sums up the sizes of the regular files within the given
+// filesystem.
+func walkDiskUsage(filesystem fs.Filesystem) (folderDiskUsage, error) {
+	usage := folderDiskUsage{Time: time.Now()}
+	err := filesystem.Walk(".", func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			// Skip what we can't access, like the scanner does.
+			return nil
+		}
+		if !info.IsRegular() {
+			return nil
+		}
+		size := info.Size()
+		usage.DiskBytes += size
+		switch {
+		case fs.IsTemporary(path):
+			usage.TempBytes += size
+		case fs.IsParent(path, ".stversions"):
+			usage.VersionBytes += size
+		case strings.Contains(filepath.Base(path), ".sync-conflict-"):
+			usage.ConflictBytes += size
+		}
+		return nil
+	})
+	return usage, err
+}
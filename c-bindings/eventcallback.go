@@ -0,0 +1,162 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"unsafe"
+
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+// #include "c_bindings.h"
+import "C"
+
+// eventForwarderQueueSize is the number of events which may be pending for
+// the event callback before further events are dropped.
+const eventForwarderQueueSize = 1000
+
+// An eventForwarder passes the events of a subscription to a deliver
+// function, e.g. one invoking the event callback. Events are queued so a slow
+// callback doesn't hold up the event logger; they are dropped if the queue is
+// full.
+type eventForwarder struct {
+	sub     events.Subscription
+	deliver func(events.Event)
+	queue   chan events.Event
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+var (
+	eventForwarderMut sync.Mutex
+	eventForwarders   = make(map[int]*eventForwarder)
+)
+
+// libst_subscribe_events subscribes to the events of the given types of the
+// given instance and passes them to the callback registered via
+// libst_set_event_callback (see c_bindings.h) with the handle of the
+// instance, their type and their data as JSON. The mask is formed by
+// or-ing the masks returned by libst_list_event_types_json. A previous
+// subscription is replaced. The events are passed from a separate thread; if
+// the callback can't keep up, events are dropped rather than delaying
+// Syncthing. The subscription ends when Syncthing stops.
+//
+//export libst_subscribe_events
+func libst_subscribe_events(handle int, mask int) int {
+	if events.EventType(mask)&events.AllEvents == 0 {
+		return statusInvalidArgument
+	}
+	// Subscribe without holding eventForwarderMut as it may take a moment.
+	sub, evLogger := subscribeRunning(handle, events.EventType(mask)&events.AllEvents)
+	if sub == nil {
+		return statusNotRunning
+	}
+
+	eventForwarderMut.Lock()
+	defer eventForwarderMut.Unlock()
+	if runningEventLogger(handle) != evLogger {
+		// The instance has stopped and removed its forwarder meanwhile.
+		sub.Unsubscribe()
+		return statusNotRunning
+	}
+	if f, ok := eventForwarders[handle]; ok {
+		f.close()
+	}
+	eventForwarders[handle] = newEventForwarder(sub, func(ev events.Event) {
+		invokeEventCallback(handle, ev)
+	})
+	return statusOK
+}
+
+// newEventForwarder starts passing the events of the given subscription to
+// the given function, which is called from a single routine.
+func newEventForwarder(sub events.Subscription, deliver func(events.Event)) *eventForwarder {
+	f := &eventForwarder{
+		sub:     sub,
+		deliver: deliver,
+		queue:   make(chan events.Event, eventForwarderQueueSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go f.receive()
+	go f.forward()
+	return f
+}
+
+// libst_unsubscribe_events ends the subscription made via
+// libst_subscribe_events for the given instance. Once it returns, the event callback isn't invoked
+// anymore, so it must not be called from within the callback.
+//
+//export libst_unsubscribe_events
+func libst_unsubscribe_events(handle int) {
+	stopEventForwarder(handle)
+}
+
+// stopEventForwarder stops and removes the event forwarder of the given
+// instance.
+func stopEventForwarder(handle int) {
+	eventForwarderMut.Lock()
+	defer eventForwarderMut.Unlock()
+	if f, ok := eventForwarders[handle]; ok {
+		f.close()
+		delete(eventForwarders, handle)
+	}
+}
+
+// close stops the forwarder and waits until the callback isn't invoked
+// anymore.
+func (f *eventForwarder) close() {
+	close(f.stop)
+	<-f.done
+}
+
+// receive moves the events of the subscription into the queue.
+func (f *eventForwarder) receive() {
+	defer f.sub.Unsubscribe()
+	for {
+		select {
+		case ev, ok := <-f.sub.C():
+			if !ok {
+				// The event logger has been stopped.
+				return
+			}
+			select {
+			case f.queue <- ev:
+			default:
+				l.Debugln("Dropping event for callback:", ev.Type)
+			}
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// forward passes the queued events to the deliver function.
+func (f *eventForwarder) forward() {
+	defer close(f.done)
+	for {
+		select {
+		case ev := <-f.queue:
+			f.deliver(ev)
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// invokeEventCallback passes the given event of the given instance to the
+// event callback.
+func invokeEventCallback(handle int, ev events.Event) {
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		l.Debugf("Marshalling data of event %v: %v", ev.Type, err)
+		return
+	}
+	C.libst_invoke_event_callback(C.int(handle), C.int(ev.Type), (*C.char)(unsafe.Pointer(&data[0])), C.size_t(len(data)))
+}
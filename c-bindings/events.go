@@ -0,0 +1,65 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// libst_get_events_since_json returns a JSON array of the events with an ID
+// greater than sinceID, like the /rest/events endpoint does. If there are no
+// such events it blocks for up to timeoutMs milliseconds until new events
+// occur. If limit is greater than zero only the last limit events are
+// returned. The last 1000 events (except local and remote change detection
+// events) are buffered, starting when Syncthing is started.
+//
+//export libst_get_events_since_json
+func libst_get_events_since_json(handle int, sinceID int, limit int, timeoutMs int) *C.char {
+	if timeoutMs < 0 {
+		return jsonError(errors.New("negative timeout"))
+	}
+	sub := runningEventSub(handle)
+	if sub == nil {
+		return jsonError(errNotRunning)
+	}
+	evs := sub.Since(sinceID, []events.Event{}, time.Duration(timeoutMs)*time.Millisecond)
+	if 0 < limit && limit < len(evs) {
+		evs = evs[len(evs)-limit:]
+	}
+	return jsonString(evs)
+}
+
+// eventTypeInfo describes an event type in the result of
+// libst_list_event_types_json.
+type eventTypeInfo struct {
+	Name string `json:"name"`
+	Mask int    `json:"mask"`
+}
+
+// libst_list_event_types_json returns a JSON array with all event types as
+// objects containing the type's name (as used for the "type" of the events)
+// and its bit within event masks. The masks of multiple types can be or-ed
+// to form a filter. The list doesn't require Syncthing to be running.
+//
+//export libst_list_event_types_json
+func libst_list_event_types_json() *C.char {
+	return jsonString(eventTypes())
+}
+
+func eventTypes() []eventTypeInfo {
+	var types []eventTypeInfo
+	for t := events.EventType(1); t&events.AllEvents != 0; t <<= 1 {
+		types = append(types, eventTypeInfo{Name: t.String(), Mask: int(t)})
+	}
+	return types
+}
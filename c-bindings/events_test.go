@@ -0,0 +1,76 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/syncthing"
+)
+
+func TestEventTypes(t *testing.T) {
+	types := eventTypes()
+	if len(types) == 0 {
+		t.Fatal("No event types")
+	}
+	all := 0
+	for _, info := range types {
+		if unmarshalled := events.UnmarshalEventType(info.Name); int(unmarshalled) != info.Mask {
+			t.Errorf("Event type %q has mask %d but unmarshals to %d", info.Name, info.Mask, unmarshalled)
+		}
+		all |= info.Mask
+	}
+	if all != int(events.AllEvents) {
+		t.Errorf("Event types cover mask %d, expected %d", all, events.AllEvents)
+	}
+}
+
+func TestSubscribeRunning(t *testing.T) {
+	evLogger := events.NewLogger()
+	go evLogger.Serve()
+	inst := &instance{app: &syncthing.App{}, evLogger: evLogger}
+	appMut.Lock()
+	handle := nextHandle
+	nextHandle++
+	instances[handle] = inst
+	appMut.Unlock()
+	defer func() {
+		appMut.Lock()
+		delete(instances, handle)
+		appMut.Unlock()
+	}()
+
+	sub, subscribed := subscribeRunning(handle, events.Starting)
+	if sub == nil || subscribed != evLogger {
+		t.Fatal("expected a subscription to the running instance")
+	}
+	sub.Unsubscribe()
+
+	// Stop the instance like libst_run_syncthing does; subscribing must not
+	// block on the stopped event logger.
+	appMut.Lock()
+	inst.app, inst.evLogger = nil, nil
+	appMut.Unlock()
+	evLogger.Stop()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if sub, _ := subscribeRunning(handle, events.Starting); sub != nil {
+			t.Error("expected no subscription to a stopped instance")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("subscribing to a stopped instance blocks")
+	}
+	if sub, _ := subscribeRunning(-1, events.Starting); sub != nil {
+		t.Error("expected no subscription for an unknown handle")
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/logger"
+)
+
+// #include "c_bindings.h"
+import "C"
+
+// logLevelFatal is the level passed to the logging callback for fatal
+// errors. It follows the levels of the logger.
+const logLevelFatal = int(logger.NumLevels)
+
+// The categories of fatal errors.
+const (
+	fatalCategoryCertificate = "certificate"
+	fatalCategoryConfig      = "config"
+	fatalCategoryConfigDir   = "configDir"
+	fatalCategoryDatabase    = "database"
+	fatalCategoryPermission  = "permission"
+	fatalCategoryStartup     = "startup"
+	fatalCategoryRuntime     = "runtime"
+)
+
+// A fatalError describes why Syncthing couldn't be started or exited
+// unexpectedly. It is passed as JSON to the logging callback.
+type fatalError struct {
+	Category         string `json:"category"`
+	Message          string `json:"message"`
+	ExitCode         int    `json:"exitCode"`
+	RestartAdvisable bool   `json:"restartAdvisable"`
+}
+
+// libst_last_error returns the message of the most recent error which made
+// libst_run_syncthing fail or Syncthing exit for the given instance, e.g. the
+// reason why the config couldn't be loaded, or an empty string if there was
+// none. It describes the error more closely than the returned exit code and
+// is cleared whenever libst_run_syncthing starts the instance.
+//
+//export libst_last_error
+func libst_last_error(handle int) *C.char {
+	appMut.RLock()
+	defer appMut.RUnlock()
+	var msg string
+	if inst, ok := instances[handle]; ok {
+		msg = inst.lastError
+	}
+	return C.CString(msg)
+}
+
+// setLastError sets the message returned by libst_last_error for the given
+// instance.
+func setLastError(handle int, msg string) {
+	appMut.Lock()
+	defer appMut.Unlock()
+	if inst, ok := instances[handle]; ok {
+		inst.lastError = msg
+	}
+}
+
+// reportFatalError logs the given error as usual and additionally passes it
+// as fatalError to the logging callback using logLevelFatal. Permission
+// errors are reported in their own category regardless of the given one as
+// they require the user's attention. The message is kept for
+// libst_last_error. Returns the given exit code.
+func reportFatalError(handle int, category string, restartAdvisable bool, exitCode int, msg string, err error) int {
+	l.Warnln(msg, err)
+	setLastError(handle, msg+" "+err.Error())
+
+	if os.IsPermission(errors.Cause(err)) {
+		category = fatalCategoryPermission
+		restartAdvisable = false
+	}
+	bytes, jsonErr := json.Marshal(fatalError{
+		Category:         category,
+		Message:          msg + " " + err.Error(),
+		ExitCode:         exitCode,
+		RestartAdvisable: restartAdvisable,
+	})
+	if jsonErr != nil {
+		return exitCode
+	}
+	// The payload is not truncated as it is useless when incomplete.
+	C.libst_invoke_logging_callback(C.int(logLevelFatal), (*C.char)(unsafe.Pointer(&bytes[0])), C.size_t(len(bytes)))
+	return exitCode
+}
+
+// reportCrash passes the given value recovered from a panic along with the
+// stack trace to the crash callback, also making it the last error of the
+// given instance.
+func reportCrash(handle int, recovered interface{}, stack []byte) {
+	msg := fmt.Sprintf("panic: %v\n\n%s", recovered, stack)
+	l.Warnln("Recovered from panic:", recovered)
+	setLastError(handle, msg)
+	bytes := []byte(msg)
+	C.libst_invoke_crash_callback(C.int(handle), (*C.char)(unsafe.Pointer(&bytes[0])), C.size_t(len(bytes)))
+}
@@ -0,0 +1,161 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+// #include "c_bindings.h"
+import "C"
+
+// folderProgressEvents are the events the folder progress callback is based
+// on.
+const folderProgressEvents = events.StateChanged | events.LocalIndexUpdated | events.FolderScanProgress
+
+// A folderProgressWatcher passes the FolderScanProgress events of the scans
+// in progress to a callback. It is only used from the routine of its event
+// forwarder.
+type folderProgressWatcher struct {
+	callback C.libst_folder_progress_callback_function_t
+	// scans holds the folders being scanned.
+	scans map[string]*folderScan
+}
+
+// A folderScan is a scan in progress.
+type folderScan struct {
+	started time.Time
+	// items is the number of items added to the index since the scan has
+	// started.
+	items int
+}
+
+var (
+	folderProgressMut        sync.Mutex
+	folderProgressForwarders = make(map[int]*eventForwarder)
+)
+
+// libst_subscribe_folder_progress registers a callback which is invoked
+// periodically while a folder of the given instance is hashing files as part
+// of a scan. It is passed the folder ID, the bytes hashed so far, the total
+// bytes to hash and the number of items committed to the index per second
+// since the scan has started. It is based on the FolderScanProgress and
+// LocalIndexUpdated events, which are received via the same mechanism as
+// the callback of libst_subscribe_events but don't need to be decoded by the
+// caller. Progress queued for a folder whose scan has finished is dropped.
+// The callback is invoked from a separate thread. Only one callback per
+// instance is supported, it replaces any previously registered one and is
+// removed when the instance stops. Once the function returns, a replaced
+// callback isn't invoked anymore, so it must not be called from within the
+// callback. Passing NULL removes the callback.
+//
+//export libst_subscribe_folder_progress
+func libst_subscribe_folder_progress(handle int, callback C.libst_folder_progress_callback_function_t) int {
+	m, err := runningModel(handle)
+	if err != nil {
+		return statusNotRunning
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	if callback == nil {
+		stopFolderProgress(handle)
+		return statusOK
+	}
+
+	// Subscribe before looking up the folders being scanned already so no
+	// state change is missed in between, but without holding
+	// folderProgressMut as it may take a moment.
+	sub, evLogger := subscribeRunning(handle, folderProgressEvents)
+	if sub == nil {
+		return statusNotRunning
+	}
+
+	folderProgressMut.Lock()
+	defer folderProgressMut.Unlock()
+	if runningEventLogger(handle) != evLogger {
+		// The instance has stopped and removed its callback meanwhile.
+		sub.Unsubscribe()
+		return statusNotRunning
+	}
+	if f, ok := folderProgressForwarders[handle]; ok {
+		f.close()
+	}
+	w := &folderProgressWatcher{
+		callback: callback,
+		scans:    make(map[string]*folderScan),
+	}
+	for id := range cfg.Folders() {
+		if state, changed, _ := m.State(id); state == "scanning" {
+			w.scans[id] = &folderScan{started: changed}
+		}
+	}
+	folderProgressForwarders[handle] = newEventForwarder(sub, w.deliver)
+	return statusOK
+}
+
+// stopFolderProgress removes the folder progress callback of the given
+// instance.
+func stopFolderProgress(handle int) {
+	folderProgressMut.Lock()
+	defer folderProgressMut.Unlock()
+	if f, ok := folderProgressForwarders[handle]; ok {
+		f.close()
+		delete(folderProgressForwarders, handle)
+	}
+}
+
+func (w *folderProgressWatcher) deliver(ev events.Event) {
+	data, ok := ev.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	folder, _ := data["folder"].(string)
+
+	switch ev.Type {
+	case events.StateChanged:
+		switch {
+		case data["to"] == "scanning":
+			w.scans[folder] = &folderScan{started: ev.Time}
+		case data["from"] == "scanning":
+			delete(w.scans, folder)
+		}
+
+	case events.LocalIndexUpdated:
+		if scan, ok := w.scans[folder]; ok {
+			items, _ := data["items"].(int)
+			scan.items += items
+		}
+
+	case events.FolderScanProgress:
+		scan, ok := w.scans[folder]
+		if !ok {
+			return
+		}
+		current, _ := data["current"].(int64)
+		total, _ := data["total"].(int64)
+		var itemsPerSecond float64
+		if elapsed := ev.Time.Sub(scan.started).Seconds(); elapsed > 0 {
+			itemsPerSecond = float64(scan.items) / elapsed
+		}
+		w.invoke(folder, current, total, itemsPerSecond)
+	}
+}
+
+func (w *folderProgressWatcher) invoke(folder string, current, total int64, itemsPerSecond float64) {
+	bytes := []byte(folder)
+	var folderID *C.char
+	if len(bytes) > 0 {
+		folderID = (*C.char)(unsafe.Pointer(&bytes[0]))
+	}
+	C.libst_invoke_folder_progress_callback(w.callback, folderID, C.size_t(len(bytes)), C.longlong(current), C.longlong(total), C.double(itemsPerSecond))
+}
@@ -0,0 +1,713 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// A folderInfo is an entry of the array returned by libst_get_folders_json.
+type folderInfo struct {
+	ID     string            `json:"id"`
+	Label  string            `json:"label"`
+	Path   string            `json:"path"`
+	Type   config.FolderType `json:"type"`
+	Paused bool              `json:"paused"`
+}
+
+// libst_get_folders_json returns a JSON array with the folders configured
+// for the given instance, sorted by folder ID. See c_bindings.h for the keys
+// of the entries. The array is empty if the instance isn't running.
+//
+//export libst_get_folders_json
+func libst_get_folders_json(handle int) *C.char {
+	folders := []folderInfo{}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return jsonString(folders)
+	}
+	for _, folder := range cfg.RawCopy().Folders {
+		folders = append(folders, folderInfo{
+			ID:     folder.ID,
+			Label:  folder.Label,
+			Path:   folder.Path,
+			Type:   folder.Type,
+			Paused: folder.Paused,
+		})
+	}
+	return jsonString(folders)
+}
+
+// libst_add_folder adds a folder with the given ID, label and path to the
+// config of the given instance, shared with no other device so far. The
+// folder type is "sendreceive", "sendonly" or "receiveonly"; empty means
+// "sendreceive". A relative path is made absolute relative to the working
+// directory, a leading ~ refers to the home directory. Returns 2 if an
+// argument is invalid and 6 if there is a folder with the ID already. The
+// folder is started and the change is persisted.
+//
+//export libst_add_folder
+func libst_add_folder(handle int, folderID string, label string, path string, folderType string) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	if folderType == "" {
+		folderType = "sendreceive"
+	}
+	newType, ok := parseFolderType(folderType)
+	if !ok || folderID == "" || path == "" {
+		return statusInvalidArgument
+	}
+	path, err := fs.ExpandTilde(path)
+	if err == nil {
+		path, err = absolutePath(path)
+	}
+	if err != nil {
+		l.Warnln("Adding folder:", err)
+		return statusInvalidArgument
+	}
+	if _, ok := cfg.Folder(folderID); ok {
+		return statusExists
+	}
+	folder := config.NewFolderConfiguration(instanceDeviceID(handle), folderID, label, fs.FilesystemTypeBasic, path)
+	folder.Type = newType
+	waiter, err := cfg.SetFolder(folder)
+	if err != nil {
+		l.Warnln("Adding folder:", err)
+		return statusFailed
+	}
+	waiter.Wait()
+	return saveConfig(cfg)
+}
+
+// libst_get_file_availability_json returns a JSON array with the IDs of the
+// connected devices which currently have a complete copy of the given file.
+// The array is empty if no such device is connected.
+//
+//export libst_get_file_availability_json
+func libst_get_file_availability_json(handle int, folderID string, path string) *C.char {
+	m, err := runningModel(handle)
+	if err != nil {
+		return jsonError(err)
+	}
+	if _, cfg := runningApp(handle); cfg == nil {
+		return jsonError(errNotRunning)
+	} else if _, ok := cfg.Folder(folderID); !ok {
+		return jsonError(errNoSuchFolder)
+	}
+	file, ok := m.CurrentGlobalFile(folderID, path)
+	if !ok {
+		return jsonString([]protocol.DeviceID{})
+	}
+	devices := []protocol.DeviceID{}
+	for _, av := range m.Availability(folderID, file, protocol.BlockInfo{}) {
+		if !av.FromTemporary {
+			devices = append(devices, av.ID)
+		}
+	}
+	return jsonString(devices)
+}
+
+// folderBandwidth is the result of libst_get_folder_bandwidth_json.
+type folderBandwidth struct {
+	Folder        string `json:"folder"`
+	BytesSent     int64  `json:"bytesSent"`
+	BytesReceived int64  `json:"bytesReceived"`
+	model.FolderTraffic
+}
+
+// libst_get_folder_bandwidth_json returns a JSON object with the bytes sent
+// and received for the given folder since Syncthing was started. The totals
+// ("bytesSent" and "bytesReceived") are broken down into file data
+// ("dataBytesSent", "dataBytesReceived") and index metadata
+// ("indexBytesSent", "indexBytesReceived"). The numbers are counted before
+// compression and don't include protocol overhead or discovery and relay
+// traffic, so they don't add up exactly to the traffic of the connections.
+//
+//export libst_get_folder_bandwidth_json
+func libst_get_folder_bandwidth_json(handle int, folderID string) *C.char {
+	m, err := runningModel(handle)
+	if err != nil {
+		return jsonError(err)
+	}
+	if _, cfg := runningApp(handle); cfg == nil {
+		return jsonError(errNotRunning)
+	} else if _, ok := cfg.Folder(folderID); !ok {
+		return jsonError(errNoSuchFolder)
+	}
+	traffic := m.FolderTraffic(folderID)
+	return jsonString(folderBandwidth{
+		Folder:        folderID,
+		BytesSent:     traffic.DataBytesSent + traffic.IndexBytesSent,
+		BytesReceived: traffic.DataBytesReceived + traffic.IndexBytesReceived,
+		FolderTraffic: traffic,
+	})
+}
+
+// maxActiveTransfers bounds the number of transfers returned by
+// libst_get_active_transfers_json.
+const maxActiveTransfers = 1000
+
+// libst_get_active_transfers_json returns a JSON object with the files
+// currently being downloaded or uploaded across all folders. The "transfers"
+// array contains at most 1000 entries, sorted by folder and path, each with
+// folder ID, path, direction ("download" or "upload"), the device for
+// uploads, and the bytes done and total. The "total" key contains the number
+// of transfers including those omitted.
+//
+//export libst_get_active_transfers_json
+func libst_get_active_transfers_json(handle int) *C.char {
+	m, err := runningModel(handle)
+	if err != nil {
+		return jsonError(err)
+	}
+	transfers, total := m.ActiveTransfers(maxActiveTransfers)
+	if transfers == nil {
+		transfers = []model.Transfer{}
+	}
+	return jsonString(map[string]interface{}{
+		"transfers": transfers,
+		"total":     total,
+	})
+}
+
+// libst_set_temp_retention sets for how many hours temporary files of
+// interrupted downloads in the given folder are kept before they are removed
+// by a scan. Zero means the global "keepTemporariesH" option applies. The
+// change is persisted and applied to the running folder.
+//
+//export libst_set_temp_retention
+func libst_set_temp_retention(handle int, folderID string, hours int) int {
+	if hours < 0 {
+		return statusInvalidArgument
+	}
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.KeepTemporariesH = hours
+	})
+}
+
+// libst_cleanup_temp_files removes the temporary files of interrupted
+// downloads in the given folder right away, regardless of their age. Files
+// currently being downloaded are kept. It returns the number of removed files
+// or a negative status code on failure.
+//
+//export libst_cleanup_temp_files
+func libst_cleanup_temp_files(handle int, folderID string) int {
+	m, err := runningModel(handle)
+	if err != nil {
+		return -statusNotRunning
+	}
+	removed, err := m.CleanTemporaries(folderID)
+	if err != nil {
+		l.Warnln("Cleaning up temporary files:", err)
+		if removed == 0 {
+			return -statusNotFound
+		}
+	}
+	return removed
+}
+
+// libst_set_folder_scan_progress_interval sets how often in seconds scan
+// progress events are emitted while the given folder is being scanned. Zero
+// means the default of two seconds and -1 disables the events. The change is
+// persisted and takes effect with the next scan without restarting the
+// folder.
+//
+//export libst_set_folder_scan_progress_interval
+func libst_set_folder_scan_progress_interval(handle int, folderID string, intervalS int) int {
+	if intervalS < -1 {
+		return statusInvalidArgument
+	}
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.ScanProgressIntervalS = intervalS
+	})
+}
+
+// libst_set_folder_scan_interval sets how often in seconds the given folder
+// is scanned fully. Zero disables the periodic scans, so changes are only
+// picked up via file system watching. Intervals above one year are capped.
+// Returns 3 if there is no such folder and 2 if the interval is negative.
+// The change is persisted and applied to the running folder by restarting
+// it.
+//
+//export libst_set_folder_scan_interval
+func libst_set_folder_scan_interval(handle int, folderID string, seconds int) int {
+	if seconds < 0 {
+		return statusInvalidArgument
+	}
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.RescanIntervalS = seconds
+	})
+}
+
+// libst_set_folder_fs_watch sets whether changes within the given folder are
+// picked up via file system notifications, so they are scanned right away
+// instead of with the next periodic scan. Returns 3 if there is no such
+// folder. The change is persisted and applied to the running folder by
+// restarting it.
+//
+//export libst_set_folder_fs_watch
+func libst_set_folder_fs_watch(handle int, folderID string, enabled bool) int {
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.FSWatcherEnabled = enabled
+	})
+}
+
+// libst_set_folder_weak_hash_threshold sets the percentage of changed blocks
+// of a file above which the weak hash is used to find blocks which moved
+// within the file when syncing it. Zero means the default of 25 percent and
+// -1 means the weak hash is always used. The change is persisted and the
+// folder is restarted.
+//
+//export libst_set_folder_weak_hash_threshold
+func libst_set_folder_weak_hash_threshold(handle int, folderID string, pct int) int {
+	if pct < -1 || pct > 100 {
+		return statusInvalidArgument
+	}
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.WeakHashThresholdPct = pct
+	})
+}
+
+// libst_set_folder_type changes the type of the given folder to
+// "sendreceive", "sendonly" or "receiveonly" and persists the change. The
+// folder is restarted with the new type. Other types (e.g.
+// "receiveencrypted") are not supported by this version and rejected as
+// invalid argument.
+//
+// Switching to "receiveonly" means local changes are no longer sent to
+// other devices; instead they are flagged as locally changed items which can
+// be reverted to the global state, so pre-existing local modifications may
+// show up as such after the next scan. Switching to "sendonly" means
+// changes of other devices are no longer applied; files differing from the
+// global state are then shown as out of sync and the local state can be
+// pushed via an override.
+//
+//export libst_set_folder_type
+func libst_set_folder_type(handle int, folderID string, folderType string) int {
+	newType, ok := parseFolderType(folderType)
+	if !ok {
+		return statusInvalidArgument
+	}
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.Type = newType
+	})
+}
+
+// parseFolderType returns the folder type with the given name as used in the
+// config. The returned bool is false if there is no such type.
+func parseFolderType(name string) (config.FolderType, bool) {
+	switch name {
+	case "sendreceive":
+		return config.FolderTypeSendReceive, true
+	case "sendonly":
+		return config.FolderTypeSendOnly, true
+	case "receiveonly":
+		return config.FolderTypeReceiveOnly, true
+	}
+	return 0, false
+}
+
+// libst_set_folder_hidden_handling sets whether files hidden by the
+// operating system (the hidden or system attribute on Windows, a leading dot
+// elsewhere) and well known system files like Thumbs.db, desktop.ini and
+// .DS_Store are excluded from syncing the given folder, as if they were
+// ignored. The setting is reflected as "ignoreHidden" in the folder config.
+// The change is persisted and the folder is restarted to apply it.
+//
+//export libst_set_folder_hidden_handling
+func libst_set_folder_hidden_handling(handle int, folderID string, ignoreHidden bool) int {
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.IgnoreHidden = ignoreHidden
+	})
+}
+
+// libst_set_folder_modtime_window sets the number of seconds by which the
+// modification times of files in the given folder may differ to still be
+// considered equal. Widening it avoids spurious rescans and conflicts on
+// filesystems with a coarse timestamp resolution like FAT. Zero means the
+// default, which is two seconds for FAT filesystems on Android and no
+// tolerance otherwise. The change is persisted and the folder is restarted
+// to apply it.
+//
+//export libst_set_folder_modtime_window
+func libst_set_folder_modtime_window(handle int, folderID string, windowSeconds int) int {
+	if windowSeconds < 0 {
+		return statusInvalidArgument
+	}
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.RawModTimeWindowS = windowSeconds
+	})
+}
+
+// libst_set_folder_copy_range_method sets how the given folder copies blocks
+// it finds in local files when pulling: "standard" (reading and writing the
+// data), "ioctl" (cloning via FICLONERANGE, e.g. on btrfs and XFS),
+// "copy_file_range", "sendfile" or "all" (trying the others in turn). Except
+// for "standard" and "all" the methods are Linux specific; methods not
+// supported on the current platform are rejected as invalid argument. If the
+// filesystem can't copy a block with the method, the block is written as
+// with "standard". The change is persisted and the folder is restarted to
+// apply it.
+//
+//export libst_set_folder_copy_range_method
+func libst_set_folder_copy_range_method(handle int, folderID string, method string) int {
+	copyRangeMethod, err := fs.ParseCopyRangeMethod(method)
+	if err != nil || !copyRangeMethod.Supported() {
+		return statusInvalidArgument
+	}
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.CopyRangeMethod = copyRangeMethod
+	})
+}
+
+// libst_set_folder_scan_on_startup sets whether the given folder is scanned
+// right after Syncthing (or the folder) starts, which is the default. If
+// disabled, the first scan is deferred to the folder's rescan interval,
+// which smooths the I/O load on startup when there are many folders; it is
+// done earlier if a scan is requested explicitly. Until then the folder
+// doesn't pull changes from other devices. Folders with periodic rescans
+// disabled are always scanned on startup. The setting is reflected as
+// "deferInitialScan" in the folder config. The change is persisted and the
+// folder is restarted to apply it.
+//
+//export libst_set_folder_scan_on_startup
+func libst_set_folder_scan_on_startup(handle int, folderID string, enabled bool) int {
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.DeferInitialScan = !enabled
+	})
+}
+
+// libst_set_folder_symlink_handling sets whether the given folder follows
+// directory junctions. The handling of links depends on the platform: on
+// Windows symlinks are not supported and always skipped by the scanner and
+// so are junctions, unless followJunctions is true, in which case they are
+// scanned and synced as the directories they point to. Followed junctions
+// pointing to a directory within the folder are synced twice and junctions
+// pointing to a parent directory lead to endless recursion, so this should
+// only be enabled for folders with known junctions. On other platforms
+// symlinks are synced as links and never followed, and there are no
+// junctions, so followJunctions has no effect. The setting is reflected as
+// "junctionsAsDirs" in the folder config. The change is persisted and the
+// folder is restarted to apply it.
+//
+//export libst_set_folder_symlink_handling
+func libst_set_folder_symlink_handling(handle int, folderID string, followJunctions bool) int {
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.JunctionsAsDirs = followJunctions
+	})
+}
+
+// libst_set_folder_paused pauses or resumes the given folder. A paused folder
+// is neither scanned nor synced with other devices. Returns 3 if there is no
+// such folder. The change can be made as soon as the config has been loaded,
+// while Syncthing is still starting up, and is persisted.
+//
+//export libst_set_folder_paused
+func libst_set_folder_paused(handle int, folderID string, paused bool) int {
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.Paused = paused
+	})
+}
+
+// libst_set_folder_quick_sync sets whether the given folder favours
+// completing small files when pulling. If enabled, the smallest files are
+// pulled first ("order" is set to "smallestFirst") with a single copier
+// ("copiers" is set to 1), which hands the blocks of one file after another
+// to the pullers, so small files tend to complete early on, at the expense
+// of the overall throughput. Several files are still pulled in parallel
+// though. If disabled, the default order and number of copiers are restored
+// unless they have been changed in the meantime. Whether quick sync is
+// enabled is reflected as "quickSync" in the folder config; changing the
+// order or copiers otherwise turns it off. The change is persisted and the
+// folder is restarted to apply it.
+//
+//export libst_set_folder_quick_sync
+func libst_set_folder_quick_sync(handle int, folderID string, enabled bool) int {
+	return updateFolder(handle, folderID, func(folder *config.FolderConfiguration) {
+		folder.SetQuickSync(enabled)
+	})
+}
+
+// libst_override_folder makes the current local state of the given send-only
+// folder the global state, overriding the changes made on other devices, like
+// the "Override Changes" button of the GUI. libst_revert_folder discards the
+// local changes made to the given receive-only folder, so the global state is
+// pulled again for the affected files, like the "Revert Local Changes" button
+// of the GUI. The operation runs in the background. Both return 3 if there is
+// no such folder, 2 if the folder doesn't have the according type and 5 if the
+// folder isn't running, e.g. because it is paused.
+//
+//export libst_override_folder
+func libst_override_folder(handle int, folderID string) int {
+	return runFolderAction(handle, folderID, func(m model.Model) {
+		m.Override(folderID)
+	}, config.FolderTypeSendOnly)
+}
+
+// libst_revert_folder: see libst_override_folder
+//
+//export libst_revert_folder
+func libst_revert_folder(handle int, folderID string) int {
+	return runFolderAction(handle, folderID, func(m model.Model) {
+		m.Revert(folderID)
+	}, config.FolderTypeReceiveOnly)
+}
+
+// libst_rescan_folder makes the given folder be scanned right away instead
+// of waiting for its scan interval. If subPath is not empty, only the file or
+// directory at this path relative to the folder root is scanned. The scan is
+// queued and the function returns without waiting for it. Returns 3 if there
+// is no such folder, 2 if the sub path is invalid, e.g. because it points
+// outside of the folder, and 5 if the folder isn't running, e.g. because it
+// is paused. Errors of the scan itself are logged.
+//
+//export libst_rescan_folder
+func libst_rescan_folder(handle int, folderID string, subPath string) int {
+	var subs []string
+	if subPath != "" {
+		sub, err := fs.Canonicalize(subPath)
+		if err != nil {
+			return statusInvalidArgument
+		}
+		if sub != "." {
+			subs = []string{sub}
+		}
+	}
+	return runFolderAction(handle, folderID, func(m model.Model) {
+		if err := m.ScanFolderSubdirs(folderID, subs); err != nil {
+			l.Infof("Scanning folder %q: %v", folderID, err)
+		}
+	})
+}
+
+// libst_rescan_all makes all running folders be scanned right away. The
+// scans are queued and the function returns without waiting for them. Errors
+// of the scans are logged.
+//
+//export libst_rescan_all
+func libst_rescan_all(handle int) int {
+	m, err := runningModel(handle)
+	if err != nil {
+		return statusNotRunning
+	}
+	go func() {
+		for folder, err := range m.ScanFolders() {
+			l.Infof("Scanning folder %q: %v", folder, err)
+		}
+	}()
+	return statusOK
+}
+
+// runFolderAction checks whether the given folder is running and has one of
+// the given types, if any are given, and starts the given action in the
+// background if so, returning an according status code.
+func runFolderAction(handle int, folderID string, action func(model.Model), folderTypes ...config.FolderType) int {
+	m, err := runningModel(handle)
+	if err != nil {
+		return statusNotRunning
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	folder, ok := cfg.Folder(folderID)
+	if !ok {
+		return statusNotFound
+	}
+	if len(folderTypes) > 0 {
+		valid := false
+		for _, folderType := range folderTypes {
+			valid = valid || folder.Type == folderType
+		}
+		if !valid {
+			return statusInvalidArgument
+		}
+	}
+	if state, _, _ := m.State(folderID); state == "" {
+		// There is no folder runner.
+		return statusBusy
+	}
+	go action(m)
+	return statusOK
+}
+
+// libst_is_folder_path_removable returns whether the given folder is located
+// on removable or external storage: 1 if it is and 0 if it isn't. The check
+// is a best effort: on Linux the removable flag of the block device is
+// checked and devices attached via USB are considered removable, on Android
+// paths below /storage are considered removable except for the internal
+// storage (/storage/emulated), and on Windows the drive type is checked, for
+// which external hard drives count as fixed. Returns -1 if it can't be
+// determined, e.g. on other platforms, for network filesystems or if
+// Syncthing isn't running, and -3 if the folder doesn't exist.
+//
+//export libst_is_folder_path_removable
+func libst_is_folder_path_removable(handle int, folderID string) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return -statusNotRunning
+	}
+	folder, ok := cfg.Folder(folderID)
+	if !ok {
+		return -statusNotFound
+	}
+	if folder.FilesystemType != fs.FilesystemTypeBasic {
+		return -1
+	}
+	removable, err := fs.IsRemovable(folder.Filesystem().URI())
+	if err != nil {
+		l.Debugf("Checking whether folder %q is on removable storage: %v", folderID, err)
+		return -1
+	}
+	if removable {
+		return 1
+	}
+	return 0
+}
+
+// maxDryRunItems bounds the number of items per kind of change returned by
+// libst_dry_run_folder.
+const maxDryRunItems = 1000
+
+// folderDryRun is the result of libst_dry_run_folder.
+type folderDryRun struct {
+	Folder string `json:"folder"`
+	model.PullPreview
+	DownloadTotal int `json:"downloadTotal"`
+	DeleteTotal   int `json:"deleteTotal"`
+	ConflictTotal int `json:"conflictTotal"`
+}
+
+// libst_dry_run_folder returns a JSON object with the changes pulling the
+// given folder would make to the local files right now, without pulling
+// anything: the paths of the files, directories and symlinks which would be
+// created or updated ("download"), deleted or moved to the versioner
+// ("delete"), and the files changed both locally and remotely which would be
+// kept as conflict copies ("conflict"). Each array contains at most 1000
+// paths; "downloadTotal", "deleteTotal" and "conflictTotal" contain the
+// numbers including those omitted. "downloadBytes" is the amount of data to
+// be fetched from other devices or copied from local files. Ignored files
+// are not included and send only folders have no changes. The changes are
+// based on the indexes received so far and may be incomplete while other
+// devices are still sending theirs. Returns an error if the folder isn't
+// running, e.g. because it is paused.
+//
+//export libst_dry_run_folder
+func libst_dry_run_folder(handle int, folderID string) *C.char {
+	m, err := runningModel(handle)
+	if err != nil {
+		return jsonError(err)
+	}
+	if _, cfg := runningApp(handle); cfg == nil {
+		return jsonError(errNotRunning)
+	} else if _, ok := cfg.Folder(folderID); !ok {
+		return jsonError(errNoSuchFolder)
+	}
+	preview, err := m.PreviewPull(folderID)
+	if err != nil {
+		return jsonError(err)
+	}
+	res := folderDryRun{
+		Folder:        folderID,
+		DownloadTotal: len(preview.Download),
+		DeleteTotal:   len(preview.Delete),
+		ConflictTotal: len(preview.Conflict),
+	}
+	preview.Download = truncateStrings(preview.Download, maxDryRunItems)
+	preview.Delete = truncateStrings(preview.Delete, maxDryRunItems)
+	preview.Conflict = truncateStrings(preview.Conflict, maxDryRunItems)
+	res.PullPreview = preview
+	return jsonString(res)
+}
+
+func truncateStrings(s []string, max int) []string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
+
+// libst_mark_folder_ready signals that the storage of the given folder is
+// available, e.g. because the host detected that the storage it is located
+// on has been mounted. It creates the folder marker (see "markerName" in the
+// folder config) unless it exists already and checks that the folder path is
+// writable. Then the folder is scanned right away, which clears the "folder
+// path missing" or "folder marker missing" error without restarting the
+// folder. The folder path itself is not created, so nothing is written to
+// the mount point while the storage isn't mounted. Returns 4 (failed) if the
+// folder path doesn't exist or isn't writable.
+//
+//export libst_mark_folder_ready
+func libst_mark_folder_ready(handle int, folderID string) int {
+	m, err := runningModel(handle)
+	if err != nil {
+		return statusNotRunning
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	folder, ok := cfg.Folder(folderID)
+	if !ok {
+		return statusNotFound
+	}
+	if err := markFolderReady(folder); err != nil {
+		l.Warnf("Marking folder %v as ready: %v", folder.Description(), err)
+		return statusFailed
+	}
+	m.DelayScan(folderID, 0)
+	return statusOK
+}
+
+// libst_is_folder_ready returns 1 if the path and the marker of the given
+// folder exist, 0 if one of them is missing, or a negative status code on
+// failure.
+//
+//export libst_is_folder_ready
+func libst_is_folder_ready(handle int, folderID string) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return -statusNotRunning
+	}
+	folder, ok := cfg.Folder(folderID)
+	if !ok {
+		return -statusNotFound
+	}
+	switch err := folder.CheckPath(); err {
+	case nil:
+		return 1
+	case config.ErrPathMissing, config.ErrMarkerMissing:
+		return 0
+	default:
+		l.Debugf("Checking folder %v: %v", folder.Description(), err)
+		return -statusFailed
+	}
+}
+
+// markFolderReady creates the marker of the given folder if it is missing
+// and checks whether the folder is writable by creating a temporary file.
+func markFolderReady(folder config.FolderConfiguration) error {
+	if err := folder.CreateMarker(); err != nil {
+		return err
+	}
+	filesystem := folder.Filesystem()
+	name := fs.TempName("ready-check")
+	fd, err := filesystem.Create(name)
+	if err != nil {
+		return err
+	}
+	fd.Close()
+	return filesystem.Remove(name)
+}
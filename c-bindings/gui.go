@@ -0,0 +1,94 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// libst_get_gui_api_key returns an API key accepted by the GUI/REST API: the
+// configured one or, if none is configured, the key passed via the guiApiKey
+// parameter of libst_run_syncthing, which is accepted in addition to the
+// configured one. Returns an empty string if neither is set and NULL if
+// Syncthing is not running.
+//
+//export libst_get_gui_api_key
+func libst_get_gui_api_key(handle int) *C.char {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return nil
+	}
+	return C.CString(cfg.GUI().AcceptedAPIKey())
+}
+
+// libst_generate_api_key returns a new random API key, generated the same way
+// as the key of a new config. It doesn't change the configured key; pass it
+// to libst_set_gui_api_key to do so, e.g. to rotate the key. It doesn't
+// require Syncthing to be running.
+//
+//export libst_generate_api_key
+func libst_generate_api_key() *C.char {
+	return C.CString(config.NewAPIKey())
+}
+
+// libst_set_gui_api_key sets the API key of the GUI/REST API and persists
+// it. If the key is empty a random key is generated. Returns the key which
+// has been set or NULL if Syncthing is not running, the key contains
+// whitespace or the config couldn't be saved.
+//
+//export libst_set_gui_api_key
+func libst_set_gui_api_key(handle int, key string) *C.char {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return nil
+	}
+	if strings.ContainsAny(key, " \t\r\n") {
+		return nil
+	}
+	if key == "" {
+		key = config.NewAPIKey()
+	}
+	gui := cfg.GUI()
+	gui.APIKey = key
+	waiter, err := cfg.SetGUI(gui)
+	if err != nil {
+		l.Warnln("Setting API key:", err)
+		return nil
+	}
+	waiter.Wait()
+	if saveConfig(cfg) != statusOK {
+		return nil
+	}
+	return C.CString(key)
+}
+
+// libst_gui_address returns the address the GUI/REST API actually listens on,
+// e.g. with the port assigned by the system if the configured address has
+// the port 0, or the path of the socket for UNIX sockets. Returns NULL if
+// Syncthing is not running, hasn't started up yet or the GUI is disabled.
+//
+//export libst_gui_address
+func libst_gui_address(handle int) *C.char {
+	app, _ := runningApp(handle)
+	if app == nil {
+		return nil
+	}
+	svc := app.APIService()
+	if svc == nil {
+		return nil
+	}
+	addr := svc.ListenerAddr()
+	if addr == nil {
+		return nil
+	}
+	return C.CString(addr.String())
+}
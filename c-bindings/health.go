@@ -0,0 +1,106 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+)
+
+// The codes returned by libst_healthcheck.
+const (
+	healthHealthy   = 0
+	healthDegraded  = 1
+	healthUnhealthy = 2
+)
+
+// healthDBTimeout is how long libst_healthcheck waits for the database.
+var healthDBTimeout = 2 * time.Second
+
+// healthProbeKey is looked up in the database to check whether it responds.
+// It doesn't exist.
+var healthProbeKey = []byte("\xffhealthcheck")
+
+// libst_healthcheck returns the health of the instance as code. It returns 0
+// (healthy) if the instance is running, its database responds and no folder
+// is in the error state. It returns 1 (degraded) if the instance is running
+// and its database responds but at least one folder is in the error state,
+// e.g. because its path is missing or there is not enough disk space. It
+// returns 2 (unhealthy) if the instance isn't running, hasn't been started
+// yet or its database doesn't respond within two seconds. It doesn't return
+// any data which could need freeing and is cheap enough to be called
+// frequently, e.g. as liveness or readiness probe.
+//
+//export libst_healthcheck
+func libst_healthcheck(handle int) int {
+	m, err := runningModel(handle)
+	if err != nil {
+		return healthUnhealthy
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil || !databaseResponds(runningDB(handle)) {
+		return healthUnhealthy
+	}
+	for id, folder := range cfg.Folders() {
+		if folder.Paused {
+			continue
+		}
+		if state, _, _ := m.State(id); state == "error" {
+			return healthDegraded
+		}
+	}
+	return healthHealthy
+}
+
+// A dbProbe is a lookup in a database which may still be in progress. ok is
+// set before done is closed.
+type dbProbe struct {
+	done chan struct{}
+	ok   bool
+}
+
+var (
+	// dbProbes holds the probes in progress per database, so a database
+	// which doesn't respond doesn't pile up a lookup per health check.
+	dbProbes    = make(map[backend.Backend]*dbProbe)
+	dbProbesMut sync.Mutex
+)
+
+// databaseResponds returns whether a lookup in the given database completes
+// without error within healthDBTimeout. A lookup which is still in progress
+// from a previous call is waited for instead of starting another one.
+func databaseResponds(db backend.Backend) bool {
+	if db == nil {
+		return false
+	}
+	dbProbesMut.Lock()
+	probe, ok := dbProbes[db]
+	if !ok {
+		probe = &dbProbe{done: make(chan struct{})}
+		dbProbes[db] = probe
+		go func() {
+			_, err := db.Get(healthProbeKey)
+			probe.ok = err == nil || backend.IsNotFound(err)
+			dbProbesMut.Lock()
+			delete(dbProbes, db)
+			dbProbesMut.Unlock()
+			close(probe.done)
+		}()
+	}
+	dbProbesMut.Unlock()
+
+	timer := time.NewTimer(healthDBTimeout)
+	defer timer.Stop()
+	select {
+	case <-probe.done:
+		return probe.ok
+	case <-timer.C:
+		return false
+	}
+}
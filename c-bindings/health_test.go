@@ -0,0 +1,68 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+)
+
+// hangingBackend is a database whose lookups block until unblock is closed.
+type hangingBackend struct {
+	backend.Backend
+	unblock chan struct{}
+	lookups int32
+}
+
+func (b *hangingBackend) Get(key []byte) ([]byte, error) {
+	atomic.AddInt32(&b.lookups, 1)
+	<-b.unblock
+	return nil, nil
+}
+
+func TestDatabaseResponds(t *testing.T) {
+	oldTimeout := healthDBTimeout
+	healthDBTimeout = 10 * time.Millisecond
+	defer func() {
+		healthDBTimeout = oldTimeout
+	}()
+
+	db := backend.OpenMemory()
+	defer db.Close()
+	if !databaseResponds(db) {
+		t.Error("a working database should respond")
+	}
+	if databaseResponds(nil) {
+		t.Error("no database should not respond")
+	}
+
+	hanging := &hangingBackend{unblock: make(chan struct{})}
+	for i := 0; i < 5; i++ {
+		if databaseResponds(hanging) {
+			t.Fatal("a hanging database should not respond")
+		}
+	}
+	if n := atomic.LoadInt32(&hanging.lookups); n != 1 {
+		t.Errorf("expected a single lookup in the hanging database, got %d", n)
+	}
+
+	// Once the lookup completes, the database responds again and the next
+	// check starts a new lookup.
+	close(hanging.unblock)
+	healthDBTimeout = time.Second
+	if !databaseResponds(hanging) {
+		t.Error("the database should respond after the lookup completed")
+	}
+	dbProbesMut.Lock()
+	defer dbProbesMut.Unlock()
+	if len(dbProbes) != 0 {
+		t.Errorf("expected no probes in progress, got %d", len(dbProbes))
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// maxPreviewedIgnores bounds the number of files returned by
+// libst_preview_ignores.
+const maxPreviewedIgnores = 1000
+
+// libst_preview_ignores returns a JSON object with the files and directories
+// of the given folder which are synced currently but would be ignored if its
+// ignore patterns were replaced with the given ones, without changing
+// anything. The patterns are given like the contents of the .stignore file,
+// one per line, and #include directives are resolved relative to the folder.
+// The "ignored" array contains at most 1000 paths as found in the local
+// index; the "total" key contains their number including those omitted.
+// Ignoring files doesn't delete them locally but other devices won't get
+// updates for them anymore. Returns an error if the folder isn't running,
+// e.g. because it is paused, or the patterns can't be parsed.
+//
+//export libst_preview_ignores
+func libst_preview_ignores(handle int, folderID string, patterns string) *C.char {
+	m, err := runningModel(handle)
+	if err != nil {
+		return jsonError(err)
+	}
+	if _, cfg := runningApp(handle); cfg == nil {
+		return jsonError(errNotRunning)
+	} else if _, ok := cfg.Folder(folderID); !ok {
+		return jsonError(errNoSuchFolder)
+	}
+	ignored, err := m.PreviewIgnores(folderID, strings.Split(patterns, "\n"))
+	if err != nil {
+		return jsonError(err)
+	}
+	total := len(ignored)
+	if total > maxPreviewedIgnores {
+		ignored = ignored[:maxPreviewedIgnores]
+	} else if ignored == nil {
+		ignored = []string{}
+	}
+	return jsonString(map[string]interface{}{
+		"folder":  folderID,
+		"ignored": ignored,
+		"total":   total,
+	})
+}
+
+// libst_export_all_ignores_json returns a JSON object mapping the ID of each
+// folder to the contents of its .stignore file, one pattern per line. The
+// contents are empty for folders without .stignore file. Files included via
+// #include directives are not exported. Folders whose ignores can't be read,
+// e.g. because the folder path is missing, are omitted and a warning is
+// logged.
+//
+//export libst_export_all_ignores_json
+func libst_export_all_ignores_json(handle int) *C.char {
+	m, err := runningModel(handle)
+	if err != nil {
+		return jsonError(err)
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return jsonError(errNotRunning)
+	}
+	ignores := make(map[string]string)
+	for id := range cfg.Folders() {
+		lines, _, err := m.GetIgnores(id)
+		if err != nil {
+			l.Warnf("Exporting ignores of folder %q: %v", id, err)
+			continue
+		}
+		ignores[id] = strings.Join(lines, "\n")
+	}
+	return jsonString(ignores)
+}
+
+// libst_import_all_ignores_json restores the .stignore files of the folders
+// from a JSON object as returned by libst_export_all_ignores_json. Folders
+// not contained in the object are left alone. If it refers to a folder which
+// doesn't exist, nothing is imported and 3 (not found) is returned. The
+// folders are rescanned with their new ignore patterns right away.
+//
+//export libst_import_all_ignores_json
+func libst_import_all_ignores_json(handle int, ignoresJSON string) int {
+	m, err := runningModel(handle)
+	if err != nil {
+		return statusNotRunning
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	var ignores map[string]string
+	if err := json.Unmarshal([]byte(ignoresJSON), &ignores); err != nil {
+		l.Warnln("Importing ignores:", err)
+		return statusInvalidArgument
+	}
+	for id := range ignores {
+		if _, ok := cfg.Folder(id); !ok {
+			return statusNotFound
+		}
+	}
+	status := statusOK
+	for id, content := range ignores {
+		var lines []string
+		if content != "" {
+			lines = strings.Split(content, "\n")
+		}
+		if err := m.SetIgnores(id, lines); err != nil {
+			l.Warnf("Importing ignores of folder %q: %v", id, err)
+			status = statusFailed
+		}
+	}
+	return status
+}
@@ -0,0 +1,195 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/syncthing"
+	"github.com/syncthing/syncthing/lib/tlsutil"
+)
+
+// An instance is a Syncthing instance which can be run via
+// libst_run_syncthing. The fields from app to changeLog are set while it is
+// running. All fields are guarded by appMut.
+type instance struct {
+	app       *syncthing.App
+	cfg       config.Wrapper
+	db        backend.Backend
+	evLogger  events.Logger
+	eventSub  events.BufferedSubscription
+	nodeState *nodeStateTracker
+	changeLog *changeLog
+
+	// active is set from the start of libst_run_syncthing until it returns.
+	active bool
+	// cancelled is closed by libst_request_shutdown; it is set while
+	// libst_run_syncthing runs.
+	cancelled chan struct{}
+	// released is closed once the last run of libst_run_syncthing has
+	// released everything, e.g. the database; nil if it hasn't been run yet.
+	released chan struct{}
+	// allowNewerConfig is the flag libst_run_syncthing has been called with;
+	// it applies to reloading the config as well.
+	allowNewerConfig bool
+	// certCommonName is the common name of a newly generated certificate,
+	// set via libst_set_certificate_common_name.
+	certCommonName string
+	// certKeyType is the key type of a newly generated certificate, set via
+	// libst_set_certificate_key_type.
+	certKeyType tlsutil.KeyType
+	myID        protocol.DeviceID
+	locations   *locations.Locations
+	lastError   string
+}
+
+var (
+	// instances maps the handles to the instances. The instance with the
+	// handle 0 always exists and uses the process wide default locations, so
+	// callers which run a single instance don't need to create one.
+	instances = map[int]*instance{
+		0: {locations: locations.Default()},
+	}
+	nextHandle = 1
+)
+
+// libst_create_instance creates a further instance and returns its handle,
+// which is passed to libst_run_syncthing and the other functions to refer to
+// it. The instance with the handle 0 exists without being created. Each
+// instance needs its own config directory, which is set when running it.
+// Instances are independent of each other and can be removed again via
+// libst_destroy_instance. Returns a negative status code on failure.
+//
+//export libst_create_instance
+func libst_create_instance() int {
+	locs, err := locations.New()
+	if err != nil {
+		l.Warnln("Creating instance:", err)
+		return -statusFailed
+	}
+	appMut.Lock()
+	defer appMut.Unlock()
+	handle := nextHandle
+	nextHandle++
+	instances[handle] = &instance{locations: locs}
+	return handle
+}
+
+// libst_destroy_instance removes an instance created via
+// libst_create_instance, so its handle becomes unknown. Its config directory
+// is left as it is. Returns 1 if the handle is unknown, 2 for the instance
+// with the handle 0, which can't be removed, and 5 if libst_run_syncthing is
+// still running for the instance.
+//
+//export libst_destroy_instance
+func libst_destroy_instance(handle int) int {
+	if handle == 0 {
+		return statusInvalidArgument
+	}
+	appMut.Lock()
+	defer appMut.Unlock()
+	inst, ok := instances[handle]
+	if !ok {
+		return statusNotRunning
+	}
+	if inst.active {
+		return statusBusy
+	}
+	delete(instances, handle)
+	return statusOK
+}
+
+// activateInstance marks the given instance as running and returns it along
+// with the released channel of its previous run, if any. It returns nil if the
+// handle is unknown or the instance is running already.
+func activateInstance(handle int) (*instance, <-chan struct{}) {
+	appMut.Lock()
+	defer appMut.Unlock()
+	inst, ok := instances[handle]
+	if !ok || inst.active {
+		return nil, nil
+	}
+	inst.active = true
+	return inst, inst.released
+}
+
+// instanceLocations returns the locations of the given instance or nil if the
+// handle is unknown.
+func instanceLocations(handle int) *locations.Locations {
+	appMut.RLock()
+	defer appMut.RUnlock()
+	if inst, ok := instances[handle]; ok {
+		return inst.locations
+	}
+	return nil
+}
+
+// instanceDeviceID returns the device ID of the given instance. It is known
+// once the instance has been run.
+func instanceDeviceID(handle int) protocol.DeviceID {
+	appMut.RLock()
+	defer appMut.RUnlock()
+	if inst, ok := instances[handle]; ok {
+		return inst.myID
+	}
+	return protocol.EmptyDeviceID
+}
+
+// libst_set_certificate_common_name sets the common name used when
+// libst_run_syncthing generates the certificate of the given instance,
+// e.g. to reflect the application embedding Syncthing. It only applies if
+// there is no certificate yet; an existing certificate is never replaced. An
+// empty name restores the default "syncthing". Other devices verify the name
+// of the certificate against the certificate name configured for the device
+// ("certName"), which defaults to "syncthing", so they can't connect unless
+// they have configured the custom name. The name applies to the next run of
+// the instance.
+//
+//export libst_set_certificate_common_name
+func libst_set_certificate_common_name(handle int, commonName string) int {
+	appMut.Lock()
+	defer appMut.Unlock()
+	inst, ok := instances[handle]
+	if !ok {
+		return statusNotRunning
+	}
+	inst.certCommonName = commonName
+	return statusOK
+}
+
+// libst_set_certificate_key_type sets the type of the key generated along
+// with the certificate of the given instance: "ecdsa-p384" (the default),
+// "ecdsa-p256" or "rsa-2048". Like the common name, it only applies if
+// libst_run_syncthing has to generate a certificate and applies to the next
+// run of the instance. An empty type restores the default. Returns 2 if the
+// type is unknown.
+//
+//export libst_set_certificate_key_type
+func libst_set_certificate_key_type(handle int, keyType string) int {
+	var kt tlsutil.KeyType
+	switch keyType {
+	case "", "ecdsa-p384":
+		kt = tlsutil.KeyTypeECDSAP384
+	case "ecdsa-p256":
+		kt = tlsutil.KeyTypeECDSAP256
+	case "rsa-2048":
+		kt = tlsutil.KeyTypeRSA2048
+	default:
+		return statusInvalidArgument
+	}
+	appMut.Lock()
+	defer appMut.Unlock()
+	inst, ok := instances[handle]
+	if !ok {
+		return statusNotRunning
+	}
+	inst.certKeyType = kt
+	return statusOK
+}
@@ -0,0 +1,43 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import "testing"
+
+func TestDestroyInstance(t *testing.T) {
+	handle := libst_create_instance()
+	if handle <= 0 {
+		t.Fatal("creating instance failed:", handle)
+	}
+
+	if inst, _ := activateInstance(handle); inst == nil {
+		t.Fatal("activating instance failed")
+	}
+	if status := libst_destroy_instance(handle); status != statusBusy {
+		t.Errorf("destroying an active instance: got %d, expected %d", status, statusBusy)
+	}
+
+	appMut.Lock()
+	instances[handle].active = false
+	appMut.Unlock()
+	if status := libst_destroy_instance(handle); status != statusOK {
+		t.Errorf("destroying instance: got %d, expected %d", status, statusOK)
+	}
+	if instanceLocations(handle) != nil {
+		t.Error("instance still exists after destroying it")
+	}
+	if status := libst_destroy_instance(handle); status != statusNotRunning {
+		t.Errorf("destroying an unknown instance: got %d, expected %d", status, statusNotRunning)
+	}
+
+	if status := libst_destroy_instance(0); status != statusInvalidArgument {
+		t.Errorf("destroying instance 0: got %d, expected %d", status, statusInvalidArgument)
+	}
+	if instanceLocations(0) == nil {
+		t.Error("instance 0 has been removed")
+	}
+}
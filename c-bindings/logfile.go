@@ -0,0 +1,139 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/logger"
+	"github.com/syncthing/syncthing/lib/osutil"
+)
+
+// Like in the monitor process of the standalone binary, the log file is
+// closed when it hasn't been written to for a while so it can be moved away.
+const (
+	logFileAutoCloseDelay = 5 * time.Second
+	logFileMaxOpenTime    = time.Minute
+)
+
+// The prefixes of the log levels, as written to stdout by the logger.
+var logLevelPrefixes = [logger.NumLevels]string{
+	logger.LevelDebug:   "DEBUG: ",
+	logger.LevelVerbose: "VERBOSE: ",
+	logger.LevelInfo:    "INFO: ",
+	logger.LevelWarn:    "WARNING: ",
+}
+
+var (
+	// logFileMut guards the variables below.
+	logFileMut sync.Mutex
+	// logFilePath, logFileMaxSize and logFileMaxFiles are set via
+	// libst_set_log_file.
+	logFilePath     string
+	logFileMaxSize  int64
+	logFileMaxFiles int
+	// logFileWriter is the open log file, if any, and logFileUsers the number
+	// of runs of libst_run_syncthing it is open for.
+	logFileWriter io.WriteCloser
+	logFileUsers  int
+	// logFileHandlerOnce makes sure the handler writing to the log file is
+	// only added once.
+	logFileHandlerOnce sync.Once
+)
+
+// libst_set_log_file makes the log messages be written to the file with the
+// given path, in addition to being passed to the logging callback, like the
+// -logfile option of the standalone binary does. The file is rotated once it
+// exceeds maxSizeMB MiB, keeping maxFiles old files named like the log file
+// with a number inserted before the extension; zero disables the rotation.
+// An empty path disables logging to a file. The file is opened when
+// libst_run_syncthing is called and closed when the last running instance
+// stops, so the setting applies to the next run. Returns 2 if the size or the
+// number of files is negative.
+//
+//export libst_set_log_file
+func libst_set_log_file(path string, maxSizeMB int, maxFiles int) int {
+	if maxSizeMB < 0 || maxFiles < 0 {
+		return statusInvalidArgument
+	}
+	if path != "" {
+		if expanded, err := fs.ExpandTilde(path); err == nil {
+			path = expanded
+		}
+	}
+	logFileMut.Lock()
+	defer logFileMut.Unlock()
+	logFilePath = path
+	logFileMaxSize = int64(maxSizeMB) << 20
+	logFileMaxFiles = maxFiles
+	return statusOK
+}
+
+// openLogFile opens the log file set via libst_set_log_file unless it is
+// open already. It must be paired with a call of closeLogFile.
+func openLogFile() {
+	// The handler is added before taking logFileMut as the logger holds its
+	// lock while calling writeLogFile.
+	logFileHandlerOnce.Do(func() {
+		l.AddHandler(logger.LevelDebug, writeLogFile)
+	})
+
+	logFileMut.Lock()
+	defer logFileMut.Unlock()
+	logFileUsers++
+	if logFileWriter != nil || logFilePath == "" {
+		return
+	}
+
+	if logFileMaxSize > 0 {
+		open := func(name string) (io.WriteCloser, error) {
+			return osutil.NewAutoclosedFile(name, logFileAutoCloseDelay, logFileMaxOpenTime), nil
+		}
+		logFileWriter = osutil.NewRotatedFile(logFilePath, open, logFileMaxSize, logFileMaxFiles)
+	} else {
+		logFileWriter = osutil.NewAutoclosedFile(logFilePath, logFileAutoCloseDelay, logFileMaxOpenTime)
+	}
+}
+
+// closeLogFile closes the log file once there is no run of
+// libst_run_syncthing left it has been opened for.
+func closeLogFile() {
+	logFileMut.Lock()
+	logFileUsers--
+	var w io.WriteCloser
+	if logFileUsers == 0 {
+		w, logFileWriter = logFileWriter, nil
+	}
+	logFileMut.Unlock()
+	if w == nil {
+		return
+	}
+	// Logging while holding logFileMut would deadlock in writeLogFile.
+	if err := w.Close(); err != nil {
+		l.Debugln("Closing log file:", err)
+	}
+}
+
+// writeLogFile writes the given message to the log file, if it is open.
+func writeLogFile(level logger.LogLevel, msg string) {
+	logFileMut.Lock()
+	defer logFileMut.Unlock()
+	if logFileWriter == nil {
+		return
+	}
+	line := time.Now().Format("2006-01-02 15:04:05 ") + logLevelPrefixes[level] + msg
+	if runtime.GOOS == "windows" {
+		line += "\r\n"
+	} else {
+		line += "\n"
+	}
+	logFileWriter.Write([]byte(line))
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+// #include "c_bindings.h"
+import "C"
+
+// lowSpaceCheckInterval is how often the free space of the folders is
+// checked once a low space callback is registered.
+const lowSpaceCheckInterval = time.Minute
+
+// A lowSpaceMonitor periodically checks the free space available to the
+// folders of an instance and invokes a callback whenever it drops below or
+// recovers above a folder's minimum.
+type lowSpaceMonitor struct {
+	cfg      config.Wrapper
+	callback C.libst_low_space_callback_function_t
+	low      map[string]bool // folder ID -> below minimum at the last check
+	stop     chan struct{}
+}
+
+var (
+	lowSpaceMut      sync.Mutex
+	lowSpaceMonitors = make(map[int]*lowSpaceMonitor)
+)
+
+// libst_set_low_space_callback registers a callback which is invoked when
+// the free space available to a folder drops below its minimum ("minDiskFree"
+// in the folder config), at which point Syncthing stops pulling changes for
+// it, and again when it recovers. It is passed the folder ID, whether the
+// free space is below the minimum and the free bytes. The free space is
+// checked right away and then every minute, so folders already low on space
+// are reported immediately; paused folders and folders without minimum are
+// not checked. Only one callback per instance is supported, it replaces any
+// previously registered one and is removed when the instance stops. Passing
+// NULL removes the callback.
+//
+//export libst_set_low_space_callback
+func libst_set_low_space_callback(handle int, callback C.libst_low_space_callback_function_t) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+
+	lowSpaceMut.Lock()
+	defer lowSpaceMut.Unlock()
+	if _, current := runningApp(handle); current != cfg {
+		// The instance has stopped and removed its monitor meanwhile.
+		return statusNotRunning
+	}
+	if m, ok := lowSpaceMonitors[handle]; ok {
+		close(m.stop)
+		delete(lowSpaceMonitors, handle)
+	}
+	if callback == nil {
+		return statusOK
+	}
+
+	m := &lowSpaceMonitor{
+		cfg:      cfg,
+		callback: callback,
+		low:      make(map[string]bool),
+		stop:     make(chan struct{}),
+	}
+	lowSpaceMonitors[handle] = m
+	go m.serve()
+	return statusOK
+}
+
+// stopLowSpaceMonitor stops and removes the low space monitor of the given
+// instance.
+func stopLowSpaceMonitor(handle int) {
+	lowSpaceMut.Lock()
+	defer lowSpaceMut.Unlock()
+	if m, ok := lowSpaceMonitors[handle]; ok {
+		close(m.stop)
+		delete(lowSpaceMonitors, handle)
+	}
+}
+
+func (m *lowSpaceMonitor) serve() {
+	ticker := time.NewTicker(lowSpaceCheckInterval)
+	defer ticker.Stop()
+	for {
+		m.check()
+		select {
+		case <-ticker.C:
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// check determines the free space of all folders and invokes the callback
+// for those which crossed their minimum since the last check.
+func (m *lowSpaceMonitor) check() {
+	folders := m.cfg.Folders()
+	for id := range m.low {
+		if folder, ok := folders[id]; !ok || folder.Paused {
+			delete(m.low, id)
+		}
+	}
+	for id, folder := range folders {
+		if folder.Paused || folder.MinDiskFree.BaseValue() <= 0 {
+			continue
+		}
+		usage, err := folder.Filesystem().Usage(".")
+		if err != nil {
+			// E.g. the folder path is missing, which is reported as folder
+			// error anyway.
+			continue
+		}
+		low := config.CheckFreeSpace(folder.MinDiskFree, usage) != nil
+		if low == m.low[id] {
+			continue
+		}
+		m.low[id] = low
+		select {
+		case <-m.stop:
+			return
+		default:
+		}
+		m.invoke(id, low, usage.Free)
+	}
+}
+
+func (m *lowSpaceMonitor) invoke(folder string, low bool, free int64) {
+	bytes := []byte(folder)
+	var folderID *C.char
+	if len(bytes) > 0 {
+		folderID = (*C.char)(unsafe.Pointer(&bytes[0]))
+	}
+	C.libst_invoke_low_space_callback(m.callback, folderID, C.size_t(len(bytes)), C.bool(low), C.longlong(free))
+}
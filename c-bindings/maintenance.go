@@ -0,0 +1,166 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/locations"
+)
+
+// maintenanceMarkerSuffix is appended to the database location to get the
+// file flagging the maintenance for the next start.
+const maintenanceMarkerSuffix = ".maintenance"
+
+// libst_schedule_database_maintenance schedules a full compaction of the
+// database of the given instance when it is started the next time via
+// libst_run_syncthing, before the folders are started. The flag is stored in
+// a file next to the database, so it persists across process restarts until
+// the maintenance has been performed. Like libst_reset_database it applies to
+// the database of the instance's current config directory, so it should be
+// called after libst_run_syncthing has been called once or when the default
+// config directory is used.
+//
+//export libst_schedule_database_maintenance
+func libst_schedule_database_maintenance(handle int) int {
+	locs := instanceLocations(handle)
+	if locs == nil {
+		return statusNotRunning
+	}
+	marker := locs.Get(locations.Database) + maintenanceMarkerSuffix
+	if err := ioutil.WriteFile(marker, []byte(time.Now().Format(time.RFC3339)+"\n"), 0600); err != nil {
+		l.Warnln("Scheduling database maintenance:", err)
+		return statusFailed
+	}
+	return statusOK
+}
+
+// libst_database_size returns the size of the database of the given instance
+// on disk in bytes. It doesn't require the instance to be running but refers
+// to the database of the instance's current config directory, like
+// libst_reset_database. Returns a negative status code on failure, e.g. -3 if
+// there is no database.
+//
+//export libst_database_size
+func libst_database_size(handle int) int64 {
+	locs := instanceLocations(handle)
+	if locs == nil {
+		return -statusNotRunning
+	}
+	var size int64
+	err := filepath.Walk(locs.Get(locations.Database), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return -statusNotFound
+	} else if err != nil {
+		l.Warnln("Determining database size:", err)
+		return -statusFailed
+	}
+	return size
+}
+
+// libst_compact_database compacts the database of the given instance to
+// reclaim the space taken by deleted and overwritten entries, which can take
+// a while for large databases. The database of its current config directory
+// is opened for the compaction, so this only works while the instance isn't
+// running; use libst_schedule_database_maintenance to compact the database
+// on the next start otherwise. Returns 5 (busy) without doing anything while
+// the instance or another instance using the same database runs, starts up
+// or shuts down, 3 if there is no database and 4 if the database can't be
+// opened, e.g. because another process uses it.
+//
+//export libst_compact_database
+func libst_compact_database(handle int) int {
+	if locs := instanceLocations(handle); locs == nil {
+		return statusNotRunning
+	}
+	inst, prevReleased := activateInstance(handle)
+	if inst == nil {
+		return statusBusy
+	}
+	defer func() {
+		appMut.Lock()
+		inst.active = false
+		appMut.Unlock()
+	}()
+	select {
+	case <-prevReleased:
+	default:
+		if prevReleased != nil {
+			// A forcefully stopped app still holds the database.
+			return statusBusy
+		}
+	}
+	location := inst.locations.Get(locations.Database)
+	if databaseInUse(location) {
+		return statusBusy
+	}
+	if _, err := os.Stat(location); err != nil {
+		l.Warnln("Opening database for compaction:", err)
+		return statusNotFound
+	}
+	ldb, err := backend.Open(location, backend.TuningAuto)
+	if err != nil {
+		l.Warnln("Opening database for compaction:", err)
+		return statusFailed
+	}
+	defer ldb.Close()
+	return compactDatabase(ldb)
+}
+
+// databaseInUse returns whether an instance has the database at the given
+// location open.
+func databaseInUse(location string) bool {
+	appMut.RLock()
+	defer appMut.RUnlock()
+	for _, inst := range instances {
+		if inst.db != nil && inst.locations.Get(locations.Database) == location {
+			return true
+		}
+	}
+	return false
+}
+
+// compactDatabase compacts the given database, logging and returning an
+// according status code.
+func compactDatabase(ldb backend.Backend) int {
+	l.Infoln("Compacting database, this may take a while")
+	t0 := time.Now()
+	if err := ldb.Compact(); err != nil {
+		l.Warnln("Compacting database:", err)
+		return statusFailed
+	}
+	l.Infof("Compacted database in %v", time.Since(t0).Truncate(time.Millisecond))
+	return statusOK
+}
+
+// performScheduledMaintenance compacts the given database if this has been
+// scheduled via libst_schedule_database_maintenance. The schedule is consumed
+// even if the compaction fails so a broken database doesn't lead to a lengthy
+// attempt on every start.
+func performScheduledMaintenance(ldb backend.Backend, location string) {
+	marker := location + maintenanceMarkerSuffix
+	if _, err := os.Stat(marker); err != nil {
+		return
+	}
+	if err := os.Remove(marker); err != nil {
+		l.Warnln("Removing database maintenance flag:", err)
+	}
+	l.Infoln("Performing scheduled database maintenance")
+	compactDatabase(ldb)
+}
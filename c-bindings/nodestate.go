@@ -0,0 +1,138 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+// #include "c_bindings.h"
+import "C"
+
+// The aggregated states passed to the node state callback.
+const (
+	nodeStateStarting = iota
+	nodeStateIdle
+	nodeStateSyncing
+	nodeStateError
+)
+
+// A nodeStateTracker aggregates the states of all folders of an instance
+// into a single state and passes it to the registered callback whenever it
+// changes.
+type nodeStateTracker struct {
+	mut      sync.Mutex
+	started  bool
+	folders  map[string]string // folder ID -> state, absent means idle
+	state    int
+	callback C.libst_node_state_callback_function_t
+}
+
+// newNodeStateTracker returns a tracker for the instance using the given
+// event logger. It is supposed to be created before the instance is started
+// and stops by itself when the event logger stops.
+func newNodeStateTracker(evLogger events.Logger) *nodeStateTracker {
+	t := &nodeStateTracker{
+		folders: make(map[string]string),
+		state:   nodeStateStarting,
+	}
+	sub := evLogger.Subscribe(events.StartupComplete | events.StateChanged | events.ConfigSaved)
+	go t.serve(sub)
+	return t
+}
+
+// libst_set_node_state_callback registers a callback which is invoked with
+// the aggregated state of all folders whenever it changes: starting (0)
+// until the startup is complete, error (3) if any folder has an error,
+// syncing (2) if any folder is scanning or syncing, and idle (1) otherwise.
+// The callback is invoked right away with the current state. Passing NULL
+// removes the callback. It is removed automatically when the instance stops.
+//
+//export libst_set_node_state_callback
+func libst_set_node_state_callback(handle int, callback C.libst_node_state_callback_function_t) int {
+	t := runningNodeStateTracker(handle)
+	if t == nil {
+		return statusNotRunning
+	}
+	t.mut.Lock()
+	t.callback = callback
+	state := t.state
+	t.mut.Unlock()
+	if callback != nil {
+		C.libst_invoke_node_state_callback(callback, C.int(state))
+	}
+	return statusOK
+}
+
+func (t *nodeStateTracker) serve(sub events.Subscription) {
+	// The channel is closed when the event logger stops.
+	for ev := range sub.C() {
+		t.mut.Lock()
+		switch ev.Type {
+		case events.StartupComplete:
+			t.started = true
+		case events.StateChanged:
+			data, ok := ev.Data.(map[string]interface{})
+			if !ok {
+				break
+			}
+			folder, _ := data["folder"].(string)
+			to, _ := data["to"].(string)
+			if to == "idle" {
+				delete(t.folders, folder)
+			} else {
+				t.folders[folder] = to
+			}
+		case events.ConfigSaved:
+			// Forget about removed and paused folders as they don't emit
+			// further state changes.
+			cfg, ok := ev.Data.(config.Configuration)
+			if !ok {
+				break
+			}
+			active := make(map[string]bool, len(cfg.Folders))
+			for _, folder := range cfg.Folders {
+				active[folder.ID] = !folder.Paused
+			}
+			for folder := range t.folders {
+				if !active[folder] {
+					delete(t.folders, folder)
+				}
+			}
+		}
+		state := t.aggregateLocked()
+		changed := state != t.state
+		t.state = state
+		callback := t.callback
+		t.mut.Unlock()
+
+		if changed && callback != nil {
+			C.libst_invoke_node_state_callback(callback, C.int(state))
+		}
+	}
+
+	t.mut.Lock()
+	t.callback = nil
+	t.mut.Unlock()
+}
+
+func (t *nodeStateTracker) aggregateLocked() int {
+	if !t.started {
+		return nodeStateStarting
+	}
+	state := nodeStateIdle
+	for _, folderState := range t.folders {
+		if folderState == "error" {
+			return nodeStateError
+		}
+		state = nodeStateSyncing
+	}
+	return state
+}
@@ -0,0 +1,259 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// defaultRelayPool is the relay listen address contained in the default
+// listen addresses.
+const defaultRelayPool = "dynamic+https://relays.syncthing.net/endpoint"
+
+// libst_set_relay_options configures how relays are used. Relays are
+// enabled for outgoing connections in any case. If announce is true the
+// device also joins relays so it can be reached via them; these are the
+// relays in the comma separated preferredRelays list (relay:// URLs or
+// dynamic+http(s):// pool URLs) or the default relay pool if the list is
+// empty. If announce is false the device doesn't join any relay. The relay
+// listeners are restarted accordingly and the change is persisted.
+//
+//export libst_set_relay_options
+func libst_set_relay_options(handle int, announce bool, preferredRelays string) int {
+	relays, err := parseRelayURLs(preferredRelays)
+	if err != nil {
+		l.Warnln("Setting relay options:", err)
+		return statusInvalidArgument
+	}
+	if len(relays) == 0 {
+		relays = []string{defaultRelayPool}
+	}
+	return updateOptions(handle, func(opts *config.OptionsConfiguration) {
+		opts.RelaysEnabled = true
+		opts.RawListenAddresses = relayListenAddresses(opts.ListenAddresses(), announce, relays)
+	})
+}
+
+// libst_set_max_outstanding_requests sets the maximum number of block
+// requests to other devices which may be in flight at the same time across
+// all folders. More requests can keep fast connections with high latency
+// busy, fewer reduce the memory used for pulling. Zero removes the limit
+// (the default) so only the per-folder limit of pending data applies;
+// negative values are invalid. The change applies to requests made from now
+// on and is persisted.
+//
+//export libst_set_max_outstanding_requests
+func libst_set_max_outstanding_requests(handle int, n int) int {
+	if n < 0 {
+		return statusInvalidArgument
+	}
+	return updateOptions(handle, func(opts *config.OptionsConfiguration) {
+		opts.MaxOutstandingRequests = n
+	})
+}
+
+// libst_set_max_connections sets the maximum number of devices which may be
+// connected at the same time. Once it is reached, connections from further
+// devices are rejected after the handshake and no further devices are
+// dialed; connections to devices which are connected already are not
+// affected, so lowering the limit doesn't drop existing connections. Zero
+// removes the limit (the default); negative values are invalid. The change
+// applies right away and is persisted.
+//
+//export libst_set_max_connections
+func libst_set_max_connections(handle int, n int) int {
+	if n < 0 {
+		return statusInvalidArgument
+	}
+	return updateOptions(handle, func(opts *config.OptionsConfiguration) {
+		opts.MaxConnections = n
+	})
+}
+
+// libst_set_global_worker_counts sets how many routines folders use by
+// default for copying blocks found locally (copiers), hashing files when
+// scanning (hashers) and requesting blocks from other devices (pullers, the
+// number of block requests in flight per folder). The values apply to folders
+// which don't set their own number of copiers and hashers in their config;
+// pullers apply to all folders. Zero restores the automatic sizing: two
+// copiers, hashers based on the number of CPU cores divided among the folders
+// (one on Windows and macOS), and pullers bound only by the folder's maximum
+// of pending data. Negative values are invalid. Running folders pick up the
+// new values with their next scan or pull, without being restarted. On
+// battery one copier and hasher are used regardless. The change is
+// persisted.
+//
+//export libst_set_global_worker_counts
+func libst_set_global_worker_counts(handle int, copiers int, hashers int, pullers int) int {
+	if copiers < 0 || hashers < 0 || pullers < 0 {
+		return statusInvalidArgument
+	}
+	return updateOptions(handle, func(opts *config.OptionsConfiguration) {
+		opts.DefaultCopiers = copiers
+		opts.DefaultHashers = hashers
+		opts.DefaultPullers = pullers
+	})
+}
+
+// libst_get_connections_json returns a JSON object with the maximum number
+// of devices which may be connected at the same time ("maxConnections", zero
+// meaning no limit) and the number of devices connected currently
+// ("connected").
+//
+//export libst_get_connections_json
+func libst_get_connections_json(handle int) *C.char {
+	m, err := runningModel(handle)
+	if err != nil {
+		return jsonError(err)
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return jsonError(errNotRunning)
+	}
+	return jsonString(map[string]int{
+		"maxConnections": cfg.Options().MaxConnections,
+		"connected":      m.NumConnections(),
+	})
+}
+
+// socketOptions are the socket options which can be configured via
+// libst_set_socket_options_json.
+type socketOptions struct {
+	TrafficClass *int `json:"trafficClass,omitempty"`
+	DSCP         *int `json:"dscp,omitempty"`
+}
+
+// libst_get_socket_options_json returns a JSON object with the socket
+// options used for connections to other devices: "trafficClass" is the
+// value of the IPv4 TOS/IPv6 traffic class byte (zero means the system
+// default) and "dscp" the DSCP contained therein.
+//
+//export libst_get_socket_options_json
+func libst_get_socket_options_json(handle int) *C.char {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return jsonError(errNotRunning)
+	}
+	trafficClass := cfg.Options().TrafficClass
+	dscp := trafficClass >> 2
+	return jsonString(socketOptions{TrafficClass: &trafficClass, DSCP: &dscp})
+}
+
+// libst_set_socket_options_json sets the socket options used for
+// connections to other devices from a JSON object. The traffic class can be
+// given either as "trafficClass" (0-255, the value of the IPv4 TOS/IPv6
+// traffic class byte) or as "dscp" (0-63, the differentiated services code
+// point, for the traffic class byte with the ECN bits unset). Zero means the
+// system default. Other socket options, like the TCP keepalive, are not
+// configurable and unknown keys are rejected as invalid. The options apply
+// to new connections and are persisted.
+//
+//export libst_set_socket_options_json
+func libst_set_socket_options_json(handle int, optionsJSON string) int {
+	var opts socketOptions
+	dec := json.NewDecoder(strings.NewReader(optionsJSON))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&opts); err != nil {
+		l.Warnln("Setting socket options:", err)
+		return statusInvalidArgument
+	}
+	var trafficClass int
+	switch {
+	case opts.TrafficClass != nil && opts.DSCP != nil:
+		return statusInvalidArgument
+	case opts.TrafficClass != nil:
+		trafficClass = *opts.TrafficClass
+		if trafficClass < 0 || trafficClass > 255 {
+			return statusInvalidArgument
+		}
+	case opts.DSCP != nil:
+		if *opts.DSCP < 0 || *opts.DSCP > 63 {
+			return statusInvalidArgument
+		}
+		trafficClass = *opts.DSCP << 2
+	default:
+		return statusOK
+	}
+	return updateOptions(handle, func(opts *config.OptionsConfiguration) {
+		opts.TrafficClass = trafficClass
+	})
+}
+
+// parseRelayURLs splits the given comma separated list of relay URLs and
+// validates them.
+func parseRelayURLs(list string) ([]string, error) {
+	var relays []string
+	for _, relay := range strings.Split(list, ",") {
+		relay = strings.TrimSpace(relay)
+		if relay == "" {
+			continue
+		}
+		uri, err := url.Parse(relay)
+		if err != nil {
+			return nil, err
+		}
+		if !isRelayScheme(uri.Scheme) || uri.Host == "" {
+			return nil, fmt.Errorf("invalid relay URL %q", relay)
+		}
+		relays = append(relays, relay)
+	}
+	return relays, nil
+}
+
+// relayListenAddresses returns the given listen addresses with the relay
+// addresses replaced by the given relays, or removed if announce is false.
+// The special "default" address is kept if the result equals the default
+// listen addresses.
+func relayListenAddresses(addrs []string, announce bool, relays []string) []string {
+	var res []string
+	for _, addr := range addrs {
+		uri, err := url.Parse(addr)
+		if err == nil && isRelayScheme(uri.Scheme) {
+			continue
+		}
+		res = append(res, addr)
+	}
+	if announce {
+		res = append(res, relays...)
+	}
+	if equalStringSets(res, config.DefaultListenAddresses) {
+		return []string{"default"}
+	}
+	return res
+}
+
+func isRelayScheme(scheme string) bool {
+	switch scheme {
+	case "relay", "dynamic+http", "dynamic+https":
+		return true
+	}
+	return false
+}
+
+func equalStringSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]struct{}, len(a))
+	for _, s := range a {
+		set[s] = struct{}{}
+	}
+	for _, s := range b {
+		if _, ok := set[s]; !ok {
+			return false
+		}
+	}
+	return true
+}
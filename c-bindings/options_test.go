@@ -0,0 +1,53 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+func TestParseRelayURLs(t *testing.T) {
+	relays, err := parseRelayURLs(" relay://1.2.3.4:22067/?id=abc, ,dynamic+https://relays.example.com/endpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"relay://1.2.3.4:22067/?id=abc", "dynamic+https://relays.example.com/endpoint"}
+	if !reflect.DeepEqual(relays, expected) {
+		t.Errorf("got %v, expected %v", relays, expected)
+	}
+
+	for _, invalid := range []string{"tcp://1.2.3.4:22000", "relay://", "relay://[::1"} {
+		if _, err := parseRelayURLs(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestRelayListenAddresses(t *testing.T) {
+	defaults := config.DefaultListenAddresses
+	custom := []string{"relay://1.2.3.4:22067"}
+
+	cases := []struct {
+		addrs    []string
+		announce bool
+		relays   []string
+		expected []string
+	}{
+		{defaults, true, []string{defaultRelayPool}, []string{"default"}},
+		{defaults, false, []string{defaultRelayPool}, []string{defaults[0], defaults[2]}},
+		{defaults, true, custom, []string{defaults[0], defaults[2], custom[0]}},
+		{[]string{"tcp://0.0.0.0:1234", "relay://5.6.7.8:22067"}, true, custom, []string{"tcp://0.0.0.0:1234", custom[0]}},
+	}
+	for _, tc := range cases {
+		if res := relayListenAddresses(tc.addrs, tc.announce, tc.relays); !reflect.DeepEqual(res, tc.expected) {
+			t.Errorf("relayListenAddresses(%v, %v, %v) = %v, expected %v", tc.addrs, tc.announce, tc.relays, res, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// A pendingDevice is an entry of the array returned by
+// libst_get_pending_devices_json.
+type pendingDevice struct {
+	DeviceID string    `json:"deviceID"`
+	Name     string    `json:"name"`
+	Address  string    `json:"address"`
+	Time     time.Time `json:"time"`
+}
+
+// libst_get_pending_devices_json returns a JSON array with the unknown
+// devices which have tried to connect to the given instance and are neither
+// configured nor ignored. See c_bindings.h for the keys of the entries. The
+// array is empty if there are no pending devices or the instance isn't
+// running.
+//
+//export libst_get_pending_devices_json
+func libst_get_pending_devices_json(handle int) *C.char {
+	devices := []pendingDevice{}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return jsonString(devices)
+	}
+	for _, pending := range cfg.RawCopy().PendingDevices {
+		devices = append(devices, pendingDevice{
+			DeviceID: pending.ID.String(),
+			Name:     pending.Name,
+			Address:  pending.Address,
+			Time:     pending.Time,
+		})
+	}
+	return jsonString(devices)
+}
+
+// libst_ignore_device dismisses the given pending device by adding it to the
+// ignored devices, so further connection attempts of it are rejected without
+// it becoming pending again. Returns 2 if the device ID is malformed and 6 if
+// the device is configured or ignored already. The change is persisted.
+//
+//export libst_ignore_device
+func libst_ignore_device(handle int, deviceID string) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	id, err := protocol.DeviceIDFromString(deviceID)
+	if err != nil {
+		return statusInvalidArgument
+	}
+	if _, ok := cfg.Device(id); ok || cfg.IgnoredDevice(id) {
+		return statusExists
+	}
+
+	newCfg := cfg.RawCopy()
+	ignored := config.ObservedDevice{
+		Time: time.Now().Round(time.Second),
+		ID:   id,
+	}
+	for _, pending := range newCfg.PendingDevices {
+		if pending.ID == id {
+			ignored.Name = pending.Name
+			ignored.Address = pending.Address
+		}
+	}
+	// The pending entry is removed when the config is prepared.
+	newCfg.IgnoredDevices = append(newCfg.IgnoredDevices, ignored)
+	waiter, err := cfg.Replace(newCfg)
+	if err != nil {
+		l.Warnln("Ignoring device:", err)
+		return statusFailed
+	}
+	waiter.Wait()
+	return saveConfig(cfg)
+}
+
+// libst_accept_all_pending accepts all pending devices and all folders
+// pending on known devices at once. Accepted devices are added with the
+// default settings. Pending folders which exist already are shared with the
+// offering device, others are added as "sendreceive" folders within the
+// default folder path, named after their label (or ID), and shared with all
+// devices offering them. As this is meant for trusted provisioning only,
+// confirm must be true; otherwise nothing happens. Returns the number of
+// accepted devices and folders or a negative status code on failure.
+//
+//export libst_accept_all_pending
+func libst_accept_all_pending(handle int, confirm bool) int {
+	if !confirm {
+		return -statusInvalidArgument
+	}
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return -statusNotRunning
+	}
+
+	newCfg := cfg.RawCopy()
+	accepted := acceptAllPending(&newCfg)
+	if accepted == 0 {
+		return 0
+	}
+	waiter, err := cfg.Replace(newCfg)
+	if err != nil {
+		l.Warnln("Accepting pending devices and folders:", err)
+		return -statusFailed
+	}
+	waiter.Wait()
+	if status := saveConfig(cfg); status != statusOK {
+		return -status
+	}
+	return accepted
+}
+
+// acceptAllPending adds the pending devices and folders to the given config
+// and returns their number.
+func acceptAllPending(cfg *config.Configuration) int {
+	accepted := 0
+	for _, pending := range cfg.PendingDevices {
+		l.Infof("Accepting pending device %v (%q at %s)", pending.ID, pending.Name, pending.Address)
+		cfg.Devices = append(cfg.Devices, config.NewDeviceConfiguration(pending.ID, pending.Name))
+		accepted++
+	}
+	cfg.PendingDevices = nil
+
+	folders := make(map[string]int, len(cfg.Folders))
+	for i, folder := range cfg.Folders {
+		folders[folder.ID] = i
+	}
+	for i := range cfg.Devices {
+		device := &cfg.Devices[i]
+		for _, pending := range device.PendingFolders {
+			index, ok := folders[pending.ID]
+			if !ok {
+				path := pendingFolderPath(cfg.Options.DefaultFolderPath, pending)
+				l.Infof("Accepting pending folder %q (%q) offered by %v at %s", pending.ID, pending.Label, device.DeviceID, path)
+				cfg.Folders = append(cfg.Folders, config.NewFolderConfiguration(cfg.MyID, pending.ID, pending.Label, fs.FilesystemTypeBasic, path))
+				index = len(cfg.Folders) - 1
+				folders[pending.ID] = index
+			} else {
+				l.Infof("Accepting pending folder %q offered by %v", pending.ID, device.DeviceID)
+			}
+			folder := &cfg.Folders[index]
+			if !folder.SharedWith(device.DeviceID) {
+				folder.Devices = append(folder.Devices, config.FolderDeviceConfiguration{DeviceID: device.DeviceID})
+			}
+			accepted++
+		}
+		device.PendingFolders = nil
+	}
+	return accepted
+}
+
+// pendingFolderPath returns the path for the given pending folder within the
+// default folder path.
+func pendingFolderPath(defaultPath string, pending config.ObservedFolder) string {
+	if expanded, err := fs.ExpandTilde(defaultPath); err == nil {
+		defaultPath = expanded
+	}
+	name := pending.Label
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		name = pending.ID
+	}
+	return filepath.Join(defaultPath, name)
+}
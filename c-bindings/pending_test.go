@@ -0,0 +1,57 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestAcceptAllPending(t *testing.T) {
+	device1, _ := protocol.DeviceIDFromString("AIR6LPZ-7K4PTTV-UXQSMUU-CPQ5YWH-OEDFIIQ-JUG777G-2YQXXR5-YD6AWQR")
+	device2, _ := protocol.DeviceIDFromString("GYRZZQB-IRNPV4Z-T7TC52W-EQYJ3TT-FDQW6MW-DFLMU42-SSSU6EM-FBK2VAY")
+	device3, _ := protocol.DeviceIDFromString("LGFPDIT-7SKNNJL-VJZA4FC-7QNCRKA-CE753K7-2BW5QDK-2FOZ7FR-FEP57QJ")
+
+	cfg := config.New(device1)
+	cfg.Options.DefaultFolderPath = "/data"
+	dev2 := config.NewDeviceConfiguration(device2, "device2")
+	dev2.PendingFolders = []config.ObservedFolder{{ID: "existing"}, {ID: "new", Label: "New Folder"}, {ID: "other", Label: "../x"}}
+	cfg.Devices = append(cfg.Devices, dev2)
+	cfg.Folders = append(cfg.Folders, config.NewFolderConfiguration(device1, "existing", "", fs.FilesystemTypeBasic, "/tmp/existing"))
+	cfg.PendingDevices = []config.ObservedDevice{{ID: device3, Name: "device3"}}
+
+	if accepted := acceptAllPending(&cfg); accepted != 4 {
+		t.Errorf("accepted %d items, expected 4", accepted)
+	}
+	if len(cfg.PendingDevices) != 0 || len(cfg.Devices[1].PendingFolders) != 0 {
+		t.Error("pending items left")
+	}
+	if len(cfg.Devices) != 3 || cfg.Devices[2].DeviceID != device3 || cfg.Devices[2].Name != "device3" {
+		t.Errorf("pending device not added: %+v", cfg.Devices)
+	}
+
+	paths := map[string]string{
+		"existing": "/tmp/existing",
+		"new":      filepath.Join("/data", "New Folder"),
+		"other":    filepath.Join("/data", "other"),
+	}
+	if len(cfg.Folders) != len(paths) {
+		t.Fatalf("got %d folders, expected %d", len(cfg.Folders), len(paths))
+	}
+	for _, folder := range cfg.Folders {
+		if folder.Path != paths[folder.ID] {
+			t.Errorf("folder %q has path %q, expected %q", folder.ID, folder.Path, paths[folder.ID])
+		}
+		if !folder.SharedWith(device1) || !folder.SharedWith(device2) {
+			t.Errorf("folder %q not shared with the expected devices: %+v", folder.ID, folder.Devices)
+		}
+	}
+}
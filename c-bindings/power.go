@@ -0,0 +1,25 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+// libst_set_battery_mode sets whether the device is running on battery.
+// While it is, only one folder is scanned at a time using a single hasher,
+// and files are pulled with a single copier and the minimum number of
+// pending requests. This overrides the configured values without changing
+// the config; passing false restores them. The concurrency of scans changes
+// right away, the other settings take effect with the next scan or pull of
+// a folder. The mode is not persisted and is off after starting.
+//
+//export libst_set_battery_mode
+func libst_set_battery_mode(handle int, onBattery bool) int {
+	m, err := runningModel(handle)
+	if err != nil {
+		return statusNotRunning
+	}
+	m.SetBatteryMode(onBattery)
+	return statusOK
+}
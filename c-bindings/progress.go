@@ -0,0 +1,199 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/model"
+)
+
+// #include "c_bindings.h"
+import "C"
+
+type etaSamplerKey struct {
+	handle int
+	folder string
+}
+
+// An etaSampler invokes a callback with the progress of a folder at a fixed
+// interval while the folder is syncing.
+type etaSampler struct {
+	folder   string
+	interval time.Duration
+	callback C.libst_folder_eta_callback_function_t
+	model    model.Model
+	sub      events.Subscription
+	stop     chan struct{}
+}
+
+var (
+	etaMut      sync.Mutex
+	etaSamplers = make(map[etaSamplerKey]*etaSampler)
+)
+
+// libst_set_folder_eta_callback registers a callback which is invoked every
+// intervalMs milliseconds while the given folder is syncing. It is passed the
+// bytes of the folder already in sync (including partially downloaded
+// files), the total bytes of the folder and the rate in bytes per second
+// since the previous invocation. Sampling pauses while the folder is not
+// syncing. Only one callback per folder is supported, it replaces any
+// previously registered one and is removed when the instance stops. Passing
+// a NULL callback or an interval of zero or less removes the callback.
+//
+//export libst_set_folder_eta_callback
+func libst_set_folder_eta_callback(handle int, folderID string, intervalMs int, callback C.libst_folder_eta_callback_function_t) int {
+	m, err := runningModel(handle)
+	if err != nil {
+		return statusNotRunning
+	}
+	if _, cfg := runningApp(handle); cfg == nil {
+		return statusNotRunning
+	} else if _, ok := cfg.Folder(folderID); !ok {
+		return statusNotFound
+	}
+
+	key := etaSamplerKey{handle, folderID}
+	if intervalMs <= 0 || callback == nil {
+		etaMut.Lock()
+		defer etaMut.Unlock()
+		if s, ok := etaSamplers[key]; ok {
+			close(s.stop)
+			delete(etaSamplers, key)
+		}
+		return statusOK
+	}
+
+	// Subscribe without holding etaMut as it may take a moment.
+	sub, evLogger := subscribeRunning(handle, events.StateChanged)
+	if sub == nil {
+		return statusNotRunning
+	}
+
+	etaMut.Lock()
+	defer etaMut.Unlock()
+	if runningEventLogger(handle) != evLogger {
+		// The instance has stopped and removed its samplers meanwhile.
+		sub.Unsubscribe()
+		return statusNotRunning
+	}
+	if s, ok := etaSamplers[key]; ok {
+		close(s.stop)
+	}
+	s := &etaSampler{
+		folder:   folderID,
+		interval: time.Duration(intervalMs) * time.Millisecond,
+		callback: callback,
+		model:    m,
+		sub:      sub,
+		stop:     make(chan struct{}),
+	}
+	etaSamplers[key] = s
+	go s.serve()
+	return statusOK
+}
+
+// stopEtaSamplers stops and removes all samplers of the given instance.
+func stopEtaSamplers(handle int) {
+	etaMut.Lock()
+	defer etaMut.Unlock()
+	for key, s := range etaSamplers {
+		if key.handle == handle {
+			close(s.stop)
+			delete(etaSamplers, key)
+		}
+	}
+}
+
+func (s *etaSampler) serve() {
+	defer s.sub.Unsubscribe()
+
+	var ticker *time.Ticker
+	var tickC <-chan time.Time
+	var lastDone int64
+	var lastTime time.Time
+	start := func() {
+		if ticker != nil {
+			return
+		}
+		ticker = time.NewTicker(s.interval)
+		tickC = ticker.C
+		lastDone, lastTime = s.bytesDone(), time.Now()
+	}
+	stop := func() {
+		if ticker == nil {
+			return
+		}
+		ticker.Stop()
+		ticker, tickC = nil, nil
+	}
+	defer stop()
+
+	if state, _, err := s.model.State(s.folder); err == nil && state == "syncing" {
+		start()
+	}
+
+	for {
+		select {
+		case ev, ok := <-s.sub.C():
+			if !ok {
+				// The event logger has been stopped.
+				return
+			}
+			data, ok := ev.Data.(map[string]interface{})
+			if !ok || data["folder"] != s.folder {
+				continue
+			}
+			if data["to"] == "syncing" {
+				start()
+			} else {
+				stop()
+			}
+
+		case now := <-tickC:
+			done := s.bytesDone()
+			total := s.model.GlobalSize(s.folder).Bytes
+			var rate float64
+			if elapsed := now.Sub(lastTime).Seconds(); elapsed > 0 && done > lastDone {
+				rate = float64(done-lastDone) / elapsed
+			}
+			lastDone, lastTime = done, now
+			s.invoke(done, total, rate)
+
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// bytesDone returns the bytes of the folder which are in sync, taking files
+// which are currently being downloaded into account.
+func (s *etaSampler) bytesDone() int64 {
+	done := s.model.GlobalSize(s.folder).Bytes - s.model.NeedSize(s.folder).Bytes
+	transfers, _ := s.model.FolderActiveTransfers(s.folder, 0)
+	for _, t := range transfers {
+		if t.Direction == model.TransferDownload {
+			done += t.BytesDone
+		}
+	}
+	if done < 0 {
+		done = 0
+	}
+	return done
+}
+
+func (s *etaSampler) invoke(done, total int64, rate float64) {
+	bytes := []byte(s.folder)
+	var folderID *C.char
+	if len(bytes) > 0 {
+		folderID = (*C.char)(unsafe.Pointer(&bytes[0]))
+	}
+	C.libst_invoke_folder_eta_callback(s.callback, folderID, C.size_t(len(bytes)), C.longlong(done), C.longlong(total), C.double(rate))
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"net/url"
+
+	"github.com/ccding/go-stun/stun"
+
+	"github.com/syncthing/syncthing/lib/connections"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// The verdicts of libst_get_reachability_json.
+const (
+	reachabilityOpen     = "open"
+	reachabilityFiltered = "filtered"
+	reachabilityBlocked  = "blocked"
+	reachabilityUnknown  = "unknown"
+)
+
+// listenerReachability is the assessment of a single listener in the result
+// of libst_get_reachability_json.
+type listenerReachability struct {
+	Reachability string   `json:"reachability"`
+	Reason       string   `json:"reason"`
+	NATType      string   `json:"natType"`
+	WANAddresses []string `json:"wanAddresses"`
+}
+
+// libst_get_reachability_json returns a JSON object with an assessment of
+// whether other devices can connect to each listener, keyed by the listener
+// address. The "reachability" is "open" if the listener is reachable from
+// outside, "filtered" if it is reachable only by some devices (e.g. behind a
+// restricted NAT), "blocked" if it isn't reachable or not working at all,
+// and "unknown" if there is not enough data. The "reason" explains the
+// verdict. The assessment is based on the port mappings made via UPnP or
+// NAT-PMP, the NAT type detected via STUN and the relay connection; no
+// active probing is done, so e.g. a TCP listener on a public address
+// without port mapping is "unknown".
+//
+//export libst_get_reachability_json
+func libst_get_reachability_json(handle int) *C.char {
+	app, _ := runningApp(handle)
+	if app == nil || app.ConnectionsService() == nil {
+		return jsonError(errNotRunning)
+	}
+	res := make(map[string]listenerReachability)
+	for addr, status := range app.ConnectionsService().ListenerStatus() {
+		res[addr] = assessReachability(addr, status)
+	}
+	return jsonString(res)
+}
+
+// assessReachability determines the reachability of the listener with the
+// given address from its status.
+func assessReachability(addr string, status connections.ListenerStatusEntry) listenerReachability {
+	res := listenerReachability{
+		Reachability: reachabilityUnknown,
+		NATType:      status.NATType,
+		WANAddresses: status.WANAddresses,
+	}
+	if res.WANAddresses == nil {
+		res.WANAddresses = []string{}
+	}
+	if status.Error != nil {
+		res.Reachability = reachabilityBlocked
+		res.Reason = "listener error: " + *status.Error
+		return res
+	}
+
+	var scheme string
+	if uri, err := url.Parse(addr); err == nil {
+		scheme = uri.Scheme
+	}
+	switch {
+	case isRelayScheme(scheme):
+		if len(status.WANAddresses) > 0 {
+			res.Reachability = reachabilityOpen
+			res.Reason = "connected to relay"
+		} else {
+			res.Reason = "not connected to a relay yet"
+		}
+
+	case scheme == "quic" || scheme == "quic4" || scheme == "quic6":
+		switch status.NATType {
+		case stun.NATNone.String(), stun.NATFull.String():
+			res.Reachability = reachabilityOpen
+		case stun.NATRestricted.String(), stun.NATPortRestricted.String():
+			res.Reachability = reachabilityFiltered
+		case stun.NATSymmetric.String(), stun.NATSymmetricUDPFirewall.String(), stun.NATBlocked.String():
+			res.Reachability = reachabilityBlocked
+		}
+		if res.Reachability == reachabilityUnknown {
+			res.Reason = "NAT type not detected via STUN"
+		} else {
+			res.Reason = "detected via STUN: " + status.NATType
+		}
+
+	default:
+		// The WAN addresses contain the LAN addresses plus the external
+		// addresses of port mappings.
+		if len(status.WANAddresses) > len(status.LANAddresses) {
+			res.Reachability = reachabilityOpen
+			res.Reason = "port mapped via UPnP/NAT-PMP"
+		} else {
+			res.Reason = "no port mapping via UPnP/NAT-PMP"
+		}
+	}
+	return res
+}
@@ -0,0 +1,161 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+// #include "c_bindings.h"
+import "C"
+
+type scanWatcherKey struct {
+	handle int
+	folder string
+}
+
+// A scanWatcher tallies the local changes a scan of a folder detects and
+// invokes a callback with the summary when the scan finishes.
+type scanWatcher struct {
+	folder   string
+	callback C.libst_scan_completed_callback_function_t
+	sub      events.Subscription
+	stop     chan struct{}
+}
+
+var (
+	scanMut      sync.Mutex
+	scanWatchers = make(map[scanWatcherKey]*scanWatcher)
+)
+
+// libst_set_scan_completed_callback registers a callback which is invoked
+// whenever a scan of the given folder finishes. It is passed the number of
+// items (files, directories and symlinks) the scan found to be added,
+// modified and deleted; all of them are zero if nothing changed. The counts
+// are based on the LocalChangeDetected events of the scan. Only one callback
+// per folder is supported, it replaces any previously registered one and is
+// removed when the instance stops. Passing NULL removes the callback.
+//
+//export libst_set_scan_completed_callback
+func libst_set_scan_completed_callback(handle int, folderID string, callback C.libst_scan_completed_callback_function_t) int {
+	if _, cfg := runningApp(handle); cfg == nil {
+		return statusNotRunning
+	} else if _, ok := cfg.Folder(folderID); !ok {
+		return statusNotFound
+	}
+
+	key := scanWatcherKey{handle, folderID}
+	if callback == nil {
+		scanMut.Lock()
+		defer scanMut.Unlock()
+		if w, ok := scanWatchers[key]; ok {
+			close(w.stop)
+			delete(scanWatchers, key)
+		}
+		return statusOK
+	}
+
+	// Subscribe without holding scanMut as it may take a moment.
+	sub, evLogger := subscribeRunning(handle, events.StateChanged|events.LocalChangeDetected)
+	if sub == nil {
+		return statusNotRunning
+	}
+
+	scanMut.Lock()
+	defer scanMut.Unlock()
+	if runningEventLogger(handle) != evLogger {
+		// The instance has stopped and removed its watchers meanwhile.
+		sub.Unsubscribe()
+		return statusNotRunning
+	}
+	if w, ok := scanWatchers[key]; ok {
+		close(w.stop)
+	}
+	w := &scanWatcher{
+		folder:   folderID,
+		callback: callback,
+		sub:      sub,
+		stop:     make(chan struct{}),
+	}
+	scanWatchers[key] = w
+	go w.serve()
+	return statusOK
+}
+
+// stopScanWatchers stops and removes all scan watchers of the given instance.
+func stopScanWatchers(handle int) {
+	scanMut.Lock()
+	defer scanMut.Unlock()
+	for key, w := range scanWatchers {
+		if key.handle == handle {
+			close(w.stop)
+			delete(scanWatchers, key)
+		}
+	}
+}
+
+func (w *scanWatcher) serve() {
+	defer w.sub.Unsubscribe()
+
+	scanning := false
+	var added, modified, deleted int
+	for {
+		select {
+		case ev, ok := <-w.sub.C():
+			if !ok {
+				// The event logger has been stopped.
+				return
+			}
+			switch ev.Type {
+			case events.StateChanged:
+				data, ok := ev.Data.(map[string]interface{})
+				if !ok || data["folder"] != w.folder {
+					continue
+				}
+				switch {
+				case data["to"] == "scanning" && !scanning:
+					scanning = true
+					added, modified, deleted = 0, 0, 0
+				case data["from"] == "scanning" && data["to"] != "scanning" && scanning:
+					scanning = false
+					w.invoke(added, modified, deleted)
+				}
+
+			case events.LocalChangeDetected:
+				// LocalChangeDetected is also emitted when overriding or
+				// reverting a folder, which doesn't count as scan.
+				data, ok := ev.Data.(map[string]string)
+				if !ok || !scanning || data["folder"] != w.folder {
+					continue
+				}
+				switch data["action"] {
+				case "added":
+					added++
+				case "deleted":
+					deleted++
+				default:
+					modified++
+				}
+			}
+
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *scanWatcher) invoke(added, modified, deleted int) {
+	bytes := []byte(w.folder)
+	var folderID *C.char
+	if len(bytes) > 0 {
+		folderID = (*C.char)(unsafe.Pointer(&bytes[0]))
+	}
+	C.libst_invoke_scan_completed_callback(w.callback, folderID, C.size_t(len(bytes)), C.int(added), C.int(modified), C.int(deleted))
+}
@@ -0,0 +1,38 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+// #include <stdlib.h>
+import "C"
+
+// libst_send_usage_report_now assembles a usage report and sends it to the
+// configured usage reporting URL ("urURL") right away, regardless of when the
+// next report is scheduled, and returns the report which was sent as JSON.
+// This is meant for testing a self-hosted usage reporting server. Returns an
+// error if usage reporting hasn't been accepted ("urAccepted" is less than 2)
+// or the report couldn't be sent; in the latter case the error object also
+// contains the report under the "report" key. The schedule of the regular
+// reports is not affected.
+//
+//export libst_send_usage_report_now
+func libst_send_usage_report_now(handle int) *C.char {
+	app, _ := runningApp(handle)
+	if app == nil || app.UsageReportingService() == nil {
+		return jsonError(errNotRunning)
+	}
+	report, err := app.UsageReportingService().SendReport()
+	if err != nil {
+		if report == nil {
+			return jsonError(err)
+		}
+		return jsonString(map[string]interface{}{
+			"error":  err.Error(),
+			"report": report,
+		})
+	}
+	return jsonString(report)
+}
@@ -0,0 +1,207 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/syncthing"
+)
+
+// #include <stdlib.h>
+import "C"
+
+// Status codes returned by the functions which return an int status unless
+// documented otherwise.
+const (
+	statusOK              = 0
+	statusNotRunning      = 1
+	statusInvalidArgument = 2
+	statusNotFound        = 3
+	statusFailed          = 4
+	statusBusy            = 5
+	statusExists          = 6
+	statusTimeout         = 7
+)
+
+var (
+	errNotRunning    = errors.New("Syncthing is not running")
+	errUnknownHandle = errors.New("unknown instance handle")
+	errNoSuchFolder  = errors.New("no such folder")
+	errNoSuchDevice  = errors.New("no such device")
+	errNotShared     = errors.New("folder not shared with device")
+	errNoConfigDir   = errors.New("no config directory given")
+)
+
+// runningInstance returns a copy of the given instance. The returned bool
+// is false if the handle is unknown or the instance isn't running.
+func runningInstance(handle int) (instance, bool) {
+	appMut.RLock()
+	defer appMut.RUnlock()
+	inst, ok := instances[handle]
+	if !ok || inst.app == nil {
+		return instance{}, false
+	}
+	return *inst, true
+}
+
+// runningApp returns the running app and its config for the given handle.
+func runningApp(handle int) (*syncthing.App, config.Wrapper) {
+	inst, _ := runningInstance(handle)
+	return inst.app, inst.cfg
+}
+
+// runningDB returns the database backend of the running app for the given
+// handle.
+func runningDB(handle int) backend.Backend {
+	inst, _ := runningInstance(handle)
+	return inst.db
+}
+
+// runningEventLogger returns the event logger of the running app for the
+// given handle.
+func runningEventLogger(handle int) events.Logger {
+	inst, _ := runningInstance(handle)
+	return inst.evLogger
+}
+
+// subscribeRunning subscribes to the given events of the running app for the
+// given handle. It returns a nil subscription if the app isn't running, and
+// the event logger subscribed to otherwise. Subscribing to a stopped event
+// logger blocks forever; the logger is only stopped after the instance's
+// fields have been cleared, so subscribing while holding appMut is safe.
+// Callers registering the subscription should check afterwards via
+// runningEventLogger that the instance hasn't been stopped in between, as it
+// wouldn't be removed then.
+func subscribeRunning(handle int, mask events.EventType) (events.Subscription, events.Logger) {
+	appMut.RLock()
+	defer appMut.RUnlock()
+	inst, ok := instances[handle]
+	if !ok || inst.app == nil || inst.evLogger == nil {
+		return nil, nil
+	}
+	return inst.evLogger.Subscribe(mask), inst.evLogger
+}
+
+// runningEventSub returns the buffered event subscription of the running app
+// for the given handle.
+func runningEventSub(handle int) events.BufferedSubscription {
+	inst, _ := runningInstance(handle)
+	return inst.eventSub
+}
+
+// runningNodeStateTracker returns the node state tracker of the running app
+// for the given handle.
+func runningNodeStateTracker(handle int) *nodeStateTracker {
+	inst, _ := runningInstance(handle)
+	return inst.nodeState
+}
+
+// runningChangeLog returns the change log of the running app for the given
+// handle.
+func runningChangeLog(handle int) *changeLog {
+	inst, _ := runningInstance(handle)
+	return inst.changeLog
+}
+
+// runningModel returns the model of the running app for the given handle.
+func runningModel(handle int) (model.Model, error) {
+	if instanceLocations(handle) == nil {
+		return nil, errUnknownHandle
+	}
+	app, _ := runningApp(handle)
+	if app == nil {
+		return nil, errNotRunning
+	}
+	m := app.Model()
+	if m == nil {
+		return nil, errNotRunning
+	}
+	return m, nil
+}
+
+// saveConfig persists the config, logging and returning an according status
+// code.
+func saveConfig(cfg config.Wrapper) int {
+	if err := cfg.Save(); err != nil {
+		l.Warnln("Saving config:", err)
+		return statusFailed
+	}
+	return statusOK
+}
+
+// absolutePath returns the given path made absolute relative to the working
+// directory, unless it is absolute already.
+func absolutePath(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	return filepath.Abs(path)
+}
+
+// updateFolder applies the given modification to the config of the specified
+// folder of the running instance, waits until it has been applied and
+// persists the config.
+func updateFolder(handle int, folderID string, modify func(folder *config.FolderConfiguration)) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	folder, ok := cfg.Folder(folderID)
+	if !ok {
+		return statusNotFound
+	}
+	modify(&folder)
+	waiter, err := cfg.SetFolder(folder)
+	if err != nil {
+		l.Warnln("Updating folder config:", err)
+		return statusFailed
+	}
+	waiter.Wait()
+	return saveConfig(cfg)
+}
+
+// updateOptions applies the given modification to the options of the running
+// instance, waits until it has been applied and persists the config.
+func updateOptions(handle int, modify func(opts *config.OptionsConfiguration)) int {
+	_, cfg := runningApp(handle)
+	if cfg == nil {
+		return statusNotRunning
+	}
+	opts := cfg.Options()
+	modify(&opts)
+	waiter, err := cfg.SetOptions(opts)
+	if err != nil {
+		l.Warnln("Updating options:", err)
+		return statusFailed
+	}
+	waiter.Wait()
+	return saveConfig(cfg)
+}
+
+// jsonString returns the JSON serialization of v as C string which must be
+// freed by the caller. Errors are returned as an object with an "error" key.
+func jsonString(v interface{}) *C.char {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return jsonError(err)
+	}
+	return C.CString(string(bs))
+}
+
+// jsonError returns an object with an "error" key containing the message of
+// the given error as C string which must be freed by the caller.
+func jsonError(err error) *C.char {
+	bs, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return C.CString(string(bs))
+}
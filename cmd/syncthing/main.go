@@ -547,6 +547,8 @@ func syncthingMain(runtimeOptions RuntimeOptions) {
 	cert, err := syncthing.LoadOrGenerateCertificate(
 		locations.Get(locations.CertFile),
 		locations.Get(locations.KeyFile),
+		tlsDefaultCommonName,
+		tlsutil.KeyTypeECDSAP384,
 	)
 	if err != nil {
 		l.Warnln("Failed to load/generate certificate:", err)
@@ -9,12 +9,10 @@ package main
 import (
 	"bufio"
 	"context"
-	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"os/signal"
-	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
@@ -57,11 +55,11 @@ func monitorMain(runtimeOptions RuntimeOptions) {
 		var fileDst io.Writer
 		if runtimeOptions.logMaxSize > 0 {
 			open := func(name string) (io.WriteCloser, error) {
-				return newAutoclosedFile(name, logFileAutoCloseDelay, logFileMaxOpenTime), nil
+				return osutil.NewAutoclosedFile(name, logFileAutoCloseDelay, logFileMaxOpenTime), nil
 			}
-			fileDst = newRotatedFile(logFile, open, int64(runtimeOptions.logMaxSize), runtimeOptions.logMaxFiles)
+			fileDst = osutil.NewRotatedFile(logFile, open, int64(runtimeOptions.logMaxSize), runtimeOptions.logMaxFiles)
 		} else {
-			fileDst = newAutoclosedFile(logFile, logFileAutoCloseDelay, logFileMaxOpenTime)
+			fileDst = osutil.NewAutoclosedFile(logFile, logFileAutoCloseDelay, logFileMaxOpenTime)
 		}
 
 		if runtime.GOOS == "windows" {
@@ -342,192 +340,6 @@ func restartMonitorWindows(args []string) error {
 	return cmd.Start()
 }
 
-// rotatedFile keeps a set of rotating logs. There will be the base file plus up
-// to maxFiles rotated ones, each ~ maxSize bytes large.
-type rotatedFile struct {
-	name        string
-	create      createFn
-	maxSize     int64 // bytes
-	maxFiles    int
-	currentFile io.WriteCloser
-	currentSize int64
-}
-
-// the createFn should act equivalently to os.Create
-type createFn func(name string) (io.WriteCloser, error)
-
-func newRotatedFile(name string, create createFn, maxSize int64, maxFiles int) *rotatedFile {
-	return &rotatedFile{
-		name:     name,
-		create:   create,
-		maxSize:  maxSize,
-		maxFiles: maxFiles,
-	}
-}
-
-func (r *rotatedFile) Write(bs []byte) (int, error) {
-	// Check if we're about to exceed the max size, and if so close this
-	// file so we'll start on a new one.
-	if r.currentSize+int64(len(bs)) > r.maxSize {
-		r.currentFile.Close()
-		r.currentFile = nil
-		r.currentSize = 0
-	}
-
-	// If we have no current log, rotate old files out of the way and create
-	// a new one.
-	if r.currentFile == nil {
-		r.rotate()
-		fd, err := r.create(r.name)
-		if err != nil {
-			return 0, err
-		}
-		r.currentFile = fd
-	}
-
-	n, err := r.currentFile.Write(bs)
-	r.currentSize += int64(n)
-	return n, err
-}
-
-func (r *rotatedFile) rotate() {
-	// The files are named "name", "name.0", "name.1", ...
-	// "name.(r.maxFiles-1)". Increase the numbers on the
-	// suffixed ones.
-	for i := r.maxFiles - 1; i > 0; i-- {
-		from := numberedFile(r.name, i-1)
-		to := numberedFile(r.name, i)
-		err := os.Rename(from, to)
-		if err != nil && !os.IsNotExist(err) {
-			fmt.Println("LOG: Rotating logs:", err)
-		}
-	}
-
-	// Rename the base to base.0
-	err := os.Rename(r.name, numberedFile(r.name, 0))
-	if err != nil && !os.IsNotExist(err) {
-		fmt.Println("LOG: Rotating logs:", err)
-	}
-}
-
-// numberedFile adds the number between the file name and the extension.
-func numberedFile(name string, num int) string {
-	ext := filepath.Ext(name) // contains the dot
-	withoutExt := name[:len(name)-len(ext)]
-	return fmt.Sprintf("%s.%d%s", withoutExt, num, ext)
-}
-
-// An autoclosedFile is an io.WriteCloser that opens itself for appending on
-// Write() and closes itself after an interval of no writes (closeDelay) or
-// when the file has been open for too long (maxOpenTime). A call to Write()
-// will return any error that happens on the resulting Open() call too. Errors
-// on automatic Close() calls are silently swallowed...
-type autoclosedFile struct {
-	name        string        // path to write to
-	closeDelay  time.Duration // close after this long inactivity
-	maxOpenTime time.Duration // or this long after opening
-
-	fd         io.WriteCloser // underlying WriteCloser
-	opened     time.Time      // timestamp when the file was last opened
-	closed     chan struct{}  // closed on Close(), stops the closerLoop
-	closeTimer *time.Timer    // fires closeDelay after a write
-
-	mut sync.Mutex
-}
-
-func newAutoclosedFile(name string, closeDelay, maxOpenTime time.Duration) *autoclosedFile {
-	f := &autoclosedFile{
-		name:        name,
-		closeDelay:  closeDelay,
-		maxOpenTime: maxOpenTime,
-		mut:         sync.NewMutex(),
-		closed:      make(chan struct{}),
-		closeTimer:  time.NewTimer(time.Minute),
-	}
-	go f.closerLoop()
-	return f
-}
-
-func (f *autoclosedFile) Write(bs []byte) (int, error) {
-	f.mut.Lock()
-	defer f.mut.Unlock()
-
-	// Make sure the file is open for appending
-	if err := f.ensureOpen(); err != nil {
-		return 0, err
-	}
-
-	// If we haven't run into the maxOpenTime, postpone close for another
-	// closeDelay
-	if time.Since(f.opened) < f.maxOpenTime {
-		f.closeTimer.Reset(f.closeDelay)
-	}
-
-	return f.fd.Write(bs)
-}
-
-func (f *autoclosedFile) Close() error {
-	f.mut.Lock()
-	defer f.mut.Unlock()
-
-	// Stop the timer and closerLoop() routine
-	f.closeTimer.Stop()
-	close(f.closed)
-
-	// Close the file, if it's open
-	if f.fd != nil {
-		return f.fd.Close()
-	}
-
-	return nil
-}
-
-// Must be called with f.mut held!
-func (f *autoclosedFile) ensureOpen() error {
-	if f.fd != nil {
-		// File is already open
-		return nil
-	}
-
-	// We open the file for write only, and create it if it doesn't exist.
-	flags := os.O_WRONLY | os.O_CREATE
-	if f.opened.IsZero() {
-		// This is the first time we are opening the file. We should truncate
-		// it to better emulate an os.Create() call.
-		flags |= os.O_TRUNC
-	} else {
-		// The file was already opened once, so we should append to it.
-		flags |= os.O_APPEND
-	}
-
-	fd, err := os.OpenFile(f.name, flags, 0644)
-	if err != nil {
-		return err
-	}
-
-	f.fd = fd
-	f.opened = time.Now()
-	return nil
-}
-
-func (f *autoclosedFile) closerLoop() {
-	for {
-		select {
-		case <-f.closeTimer.C:
-			// Close the file when the timer expires.
-			f.mut.Lock()
-			if f.fd != nil {
-				f.fd.Close() // errors, schmerrors
-				f.fd = nil
-			}
-			f.mut.Unlock()
-
-		case <-f.closed:
-			return
-		}
-	}
-}
-
 // Returns the desired child environment, properly filtered and added to.
 func childEnv() []string {
 	var env []string
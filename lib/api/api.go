@@ -85,11 +85,13 @@ type service struct {
 	contr                Controller
 	noUpgrade            bool
 	tlsDefaultCommonName string
+	locations            *locations.Locations
 	configChanged        chan struct{} // signals intentional listener close due to config change
 	started              chan string   // signals startup complete by sending the listener address, for testing only
 	startedOnce          chan struct{} // the service has started successfully at least once
 	startupErr           error
 	listenerAddr         net.Addr
+	listenerAddrMut      sync.Mutex
 
 	guiErrors logger.Recorder
 	systemLog logger.Recorder
@@ -109,9 +111,10 @@ type Service interface {
 	suture.Service
 	config.Committer
 	WaitForStart() error
+	ListenerAddr() net.Addr
 }
 
-func New(id protocol.DeviceID, cfg config.Wrapper, assetDir, tlsDefaultCommonName string, m model.Model, defaultSub, diskSub events.BufferedSubscription, evLogger events.Logger, discoverer discover.CachingMux, connectionsService connections.Service, urService *ur.Service, fss model.FolderSummaryService, errors, systemLog logger.Recorder, cpu Rater, contr Controller, noUpgrade bool) Service {
+func New(id protocol.DeviceID, cfg config.Wrapper, assetDir, tlsDefaultCommonName string, locs *locations.Locations, m model.Model, defaultSub, diskSub events.BufferedSubscription, evLogger events.Logger, discoverer discover.CachingMux, connectionsService connections.Service, urService *ur.Service, fss model.FolderSummaryService, errors, systemLog logger.Recorder, cpu Rater, contr Controller, noUpgrade bool) Service {
 	s := &service{
 		id:      id,
 		cfg:     cfg,
@@ -128,12 +131,14 @@ func New(id protocol.DeviceID, cfg config.Wrapper, assetDir, tlsDefaultCommonNam
 		fss:                  fss,
 		urService:            urService,
 		systemConfigMut:      sync.NewMutex(),
+		listenerAddrMut:      sync.NewMutex(),
 		guiErrors:            errors,
 		systemLog:            systemLog,
 		cpu:                  cpu,
 		contr:                contr,
 		noUpgrade:            noUpgrade,
 		tlsDefaultCommonName: tlsDefaultCommonName,
+		locations:            locs,
 		configChanged:        make(chan struct{}),
 		startedOnce:          make(chan struct{}),
 	}
@@ -146,9 +151,17 @@ func (s *service) WaitForStart() error {
 	return s.startupErr
 }
 
+// ListenerAddr returns the address the GUI/REST API listens on, or nil if it
+// isn't listening yet.
+func (s *service) ListenerAddr() net.Addr {
+	s.listenerAddrMut.Lock()
+	defer s.listenerAddrMut.Unlock()
+	return s.listenerAddr
+}
+
 func (s *service) getListener(guiCfg config.GUIConfiguration) (net.Listener, error) {
-	httpsCertFile := locations.Get(locations.HTTPSCertFile)
-	httpsKeyFile := locations.Get(locations.HTTPSKeyFile)
+	httpsCertFile := s.locations.Get(locations.HTTPSCertFile)
+	httpsKeyFile := s.locations.Get(locations.HTTPSKeyFile)
 	cert, err := tls.LoadX509KeyPair(httpsCertFile, httpsKeyFile)
 
 	// If the certificate has expired or will expire in the next month, fail
@@ -233,7 +246,9 @@ func (s *service) serve(ctx context.Context) {
 		return
 	}
 
+	s.listenerAddrMut.Lock()
 	s.listenerAddr = listener.Addr()
+	s.listenerAddrMut.Unlock()
 	defer listener.Close()
 
 	s.cfg.Subscribe(s)
@@ -322,7 +337,7 @@ func (s *service) serve(ctx context.Context) {
 
 	// Wrap everything in CSRF protection. The /rest prefix should be
 	// protected, other requests will grant cookies.
-	var handler http.Handler = newCsrfManager(s.id.String()[:5], "/rest", guiCfg, mux, locations.Get(locations.CsrfTokens))
+	var handler http.Handler = newCsrfManager(s.id.String()[:5], "/rest", guiCfg, mux, s.locations.Get(locations.CsrfTokens))
 
 	// Add our version and ID as a header to responses
 	handler = withDetailsMiddleware(s.id, handler)
@@ -941,7 +956,7 @@ func (s *service) getSystemStatus(w http.ResponseWriter, r *http.Request) {
 	res["uptime"] = s.urService.UptimeS()
 	res["startTime"] = ur.StartTime
 	res["guiAddressOverridden"] = s.cfg.GUI().IsOverridden()
-	res["guiAddressUsed"] = s.listenerAddr.String()
+	res["guiAddressUsed"] = s.ListenerAddr().String()
 
 	sendJSON(w, res)
 }
@@ -1018,7 +1033,7 @@ func (s *service) getSupportBundle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Panic files
-	if panicFiles, err := filepath.Glob(filepath.Join(locations.GetBaseDir(locations.ConfigBaseDir), "panic*")); err == nil {
+	if panicFiles, err := filepath.Glob(filepath.Join(s.locations.GetBaseDir(locations.ConfigBaseDir), "panic*")); err == nil {
 		for _, f := range panicFiles {
 			if panicFile, err := ioutil.ReadFile(f); err != nil {
 				l.Warnf("Support bundle: failed to load %s: %s", filepath.Base(f), err)
@@ -1029,7 +1044,7 @@ func (s *service) getSupportBundle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Archived log (default on Windows)
-	if logFile, err := ioutil.ReadFile(locations.Get(locations.LogFile)); err == nil {
+	if logFile, err := ioutil.ReadFile(s.locations.Get(locations.LogFile)); err == nil {
 		files = append(files, fileEntry{name: "log-ondisk.txt", data: logFile})
 	}
 
@@ -1080,7 +1095,7 @@ func (s *service) getSupportBundle(w http.ResponseWriter, r *http.Request) {
 
 	// Set zip file name and path
 	zipFileName := fmt.Sprintf("support-bundle-%s-%s.zip", s.id.Short().String(), time.Now().Format("2006-01-02T150405"))
-	zipFilePath := filepath.Join(locations.GetBaseDir(locations.ConfigBaseDir), zipFileName)
+	zipFilePath := filepath.Join(s.locations.GetBaseDir(locations.ConfigBaseDir), zipFileName)
 
 	// Write buffer zip to local zip file (back up)
 	if err := ioutil.WriteFile(zipFilePath, zipFilesBuffer.Bytes(), 0600); err != nil {
@@ -86,6 +86,8 @@ func (c *mockedConfig) SetGUI(gui config.GUIConfiguration) (config.Waiter, error
 	return noopWaiter{}, nil
 }
 
+func (c *mockedConfig) SetGUIOverrides(address, apiKey string) {}
+
 func (c *mockedConfig) SetOptions(opts config.OptionsConfiguration) (config.Waiter, error) {
 	return noopWaiter{}, nil
 }
@@ -50,3 +50,9 @@ func (m *mockedCachingMux) Add(finder discover.Finder, cacheTime, negCacheTime t
 func (m *mockedCachingMux) ChildErrors() map[string]error {
 	return nil
 }
+
+func (m *mockedCachingMux) SetCacheTime(cacheTime time.Duration) {
+}
+
+func (m *mockedCachingMux) ClearCache() {
+}
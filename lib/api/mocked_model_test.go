@@ -28,6 +28,24 @@ func (m *mockedModel) Completion(device protocol.DeviceID, folder string) model.
 	return model.FolderCompletion{}
 }
 
+func (m *mockedModel) ActiveTransfers(limit int) ([]model.Transfer, int) {
+	return nil, 0
+}
+
+func (m *mockedModel) FolderActiveTransfers(folder string, limit int) ([]model.Transfer, int) {
+	return nil, 0
+}
+
+func (m *mockedModel) CleanTemporaries(folder string) (int, error) {
+	return 0, nil
+}
+
+func (m *mockedModel) SetBatteryMode(onBattery bool) {}
+
+func (m *mockedModel) FolderTraffic(folder string) model.FolderTraffic {
+	return model.FolderTraffic{}
+}
+
 func (m *mockedModel) Override(folder string) {}
 
 func (m *mockedModel) Revert(folder string) {}
@@ -79,6 +97,14 @@ func (m *mockedModel) SetIgnores(folder string, content []string) error {
 	return nil
 }
 
+func (m *mockedModel) PreviewIgnores(folder string, content []string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) PreviewPull(folder string) (model.PullPreview, error) {
+	return model.PullPreview{}, nil
+}
+
 func (m *mockedModel) GetFolderVersions(folder string) (map[string][]versioner.FileVersion, error) {
 	return nil, nil
 }
@@ -183,6 +209,10 @@ func (m *mockedModel) OnHello(protocol.DeviceID, net.Addr, protocol.HelloResult)
 	return nil
 }
 
+func (m *mockedModel) NumConnections() int {
+	return 0
+}
+
 func (m *mockedModel) GetHello(protocol.DeviceID) protocol.HelloIntf {
 	return nil
 }
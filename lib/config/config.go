@@ -25,7 +25,6 @@ import (
 
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/protocol"
-	"github.com/syncthing/syncthing/lib/rand"
 	"github.com/syncthing/syncthing/lib/util"
 )
 
@@ -355,7 +354,7 @@ func (cfg *Configuration) clean() error {
 	}
 
 	if cfg.GUI.APIKey == "" {
-		cfg.GUI.APIKey = rand.String(32)
+		cfg.GUI.APIKey = NewAPIKey()
 	}
 
 	// The list of ignored devices should not contain any devices that have
@@ -690,6 +690,50 @@ func TestGUIConfigURL(t *testing.T) {
 	}
 }
 
+func TestGUIOverrides(t *testing.T) {
+	wrapper, err := load("testdata/example.xml", device1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapper.SetGUIOverrides("https://127.0.0.1:9999", "overridden-key")
+
+	gui := wrapper.GUI()
+	if !gui.IsOverridden() {
+		t.Error("GUI address should be overridden")
+	}
+	if addr := gui.Address(); addr != "127.0.0.1:9999" {
+		t.Errorf("Incorrect address %q", addr)
+	}
+	if !gui.UseTLS() {
+		t.Error("Overridden address should enable TLS")
+	}
+	if !gui.IsValidAPIKey("overridden-key") {
+		t.Error("Overridden API key should be valid")
+	}
+	if key := gui.AcceptedAPIKey(); key != gui.APIKey {
+		t.Errorf("Accepted API key %q should be the configured one", key)
+	}
+	gui.APIKey = ""
+	if key := gui.AcceptedAPIKey(); key != "overridden-key" {
+		t.Errorf("Accepted API key %q should be the overridden one without configured key", key)
+	}
+
+	// The overrides must not be persisted, neither directly nor when
+	// setting the GUI configuration obtained from the wrapper.
+	if _, err := wrapper.SetGUI(gui); err != nil {
+		t.Fatal(err)
+	}
+	raw := wrapper.RawCopy().GUI
+	if raw.IsOverridden() || raw.IsValidAPIKey("overridden-key") {
+		t.Error("Overrides should not be part of the raw config")
+	}
+
+	wrapper.SetGUIOverrides("", "")
+	if wrapper.GUI().IsOverridden() {
+		t.Error("GUI address should not be overridden anymore")
+	}
+}
+
 func TestDuplicateDevices(t *testing.T) {
 	// Duplicate devices should be removed
 
@@ -1127,6 +1171,52 @@ func TestRemoveDeviceWithEmptyID(t *testing.T) {
 	}
 }
 
+func TestFolderQuickSync(t *testing.T) {
+	fcfg := NewFolderConfiguration(device1, "default", "default", fs.FilesystemTypeBasic, "testdata")
+	fcfg.Order = OrderNewestFirst
+	fcfg.Copiers = 4
+
+	fcfg.SetQuickSync(true)
+	if fcfg.Order != OrderSmallestFirst || fcfg.Copiers != 1 || !fcfg.QuickSync {
+		t.Errorf("Quick sync not enabled: order %v, copiers %d, quick sync %v", fcfg.Order, fcfg.Copiers, fcfg.QuickSync)
+	}
+	fcfg.SetQuickSync(false)
+	if fcfg.Order != OrderRandom || fcfg.Copiers != 0 || fcfg.QuickSync {
+		t.Errorf("Quick sync not disabled: order %v, copiers %d, quick sync %v", fcfg.Order, fcfg.Copiers, fcfg.QuickSync)
+	}
+
+	// Disabling keeps a custom order.
+	fcfg.Order = OrderNewestFirst
+	fcfg.SetQuickSync(false)
+	if fcfg.Order != OrderNewestFirst {
+		t.Errorf("Custom order %v not kept", fcfg.Order)
+	}
+
+	// Disabling keeps identical settings made manually.
+	fcfg.Order = OrderSmallestFirst
+	fcfg.Copiers = 1
+	fcfg.SetQuickSync(false)
+	if fcfg.Order != OrderSmallestFirst || fcfg.Copiers != 1 || fcfg.QuickSync {
+		t.Errorf("Manual settings not kept: order %v, copiers %d, quick sync %v", fcfg.Order, fcfg.Copiers, fcfg.QuickSync)
+	}
+	fcfg.prepare()
+	if fcfg.QuickSync {
+		t.Error("Manual settings detected as quick sync")
+	}
+
+	// Changing the settings manually turns quick sync off when loading.
+	fcfg.SetQuickSync(true)
+	fcfg.prepare()
+	if !fcfg.QuickSync {
+		t.Error("Quick sync not kept")
+	}
+	fcfg.Copiers = 2
+	fcfg.prepare()
+	if fcfg.QuickSync {
+		t.Error("Quick sync kept after changing the copiers")
+	}
+}
+
 // defaultConfigAsMap returns a valid default config as a JSON-decoded
 // map[string]interface{}. This is useful to override random elements and
 // re-encode into JSON.
@@ -47,7 +47,7 @@ type FolderConfiguration struct {
 	Hashers                 int                         `xml:"hashers" json:"hashers"` // Less than one sets the value to the number of cores. These are CPU bound due to hashing.
 	Order                   PullOrder                   `xml:"order" json:"order"`
 	IgnoreDelete            bool                        `xml:"ignoreDelete" json:"ignoreDelete"`
-	ScanProgressIntervalS   int                         `xml:"scanProgressIntervalS" json:"scanProgressIntervalS"` // Set to a negative value to disable. Value of 0 will get replaced with value of 2 (default value)
+	ScanProgressIntervalS   int                         `xml:"scanProgressIntervalS" json:"scanProgressIntervalS" restart:"false"` // Set to a negative value to disable. Value of 0 will get replaced with value of 2 (default value)
 	PullerPauseS            int                         `xml:"pullerPauseS" json:"pullerPauseS"`
 	MaxConflicts            int                         `xml:"maxConflicts" json:"maxConflicts" default:"-1"`
 	DisableSparseFiles      bool                        `xml:"disableSparseFiles" json:"disableSparseFiles"`
@@ -57,6 +57,12 @@ type FolderConfiguration struct {
 	MarkerName              string                      `xml:"markerName" json:"markerName"`
 	CopyOwnershipFromParent bool                        `xml:"copyOwnershipFromParent" json:"copyOwnershipFromParent"`
 	RawModTimeWindowS       int                         `xml:"modTimeWindowS" json:"modTimeWindowS"`
+	KeepTemporariesH        int                         `xml:"keepTemporariesH" json:"keepTemporariesH"` // Overrides the global option if larger than zero.
+	IgnoreHidden            bool                        `xml:"ignoreHidden" json:"ignoreHidden"`         // Don't sync files hidden by the OS and well known system files.
+	CopyRangeMethod         fs.CopyRangeMethod          `xml:"copyRangeMethod" json:"copyRangeMethod"`   // How blocks found in local files are copied when pulling.
+	DeferInitialScan        bool                        `xml:"deferInitialScan" json:"deferInitialScan"` // Do the first scan after the rescan interval instead of right after starting.
+	JunctionsAsDirs         bool                        `xml:"junctionsAsDirs" json:"junctionsAsDirs"`   // Follow directory junctions on Windows instead of skipping them like symlinks.
+	QuickSync               bool                        `xml:"quickSync" json:"quickSync"`               // Read only, whether SetQuickSync has set up Order and Copiers and they haven't been changed since.
 
 	cachedFilesystem    fs.Filesystem
 	cachedModTimeWindow time.Duration
@@ -99,11 +105,19 @@ func (f FolderConfiguration) Filesystem() fs.Filesystem {
 	// cfg.Folders["default"].Filesystem() should be valid.
 	if f.cachedFilesystem == nil {
 		l.Infoln("bug: uncached filesystem call (should only happen in tests)")
-		return fs.NewFilesystem(f.FilesystemType, f.Path)
+		return fs.NewFilesystem(f.FilesystemType, f.Path, f.filesystemOptions()...)
 	}
 	return f.cachedFilesystem
 }
 
+func (f FolderConfiguration) filesystemOptions() []fs.Option {
+	var opts []fs.Option
+	if f.JunctionsAsDirs {
+		opts = append(opts, fs.WithJunctionsAsDirs())
+	}
+	return opts
+}
+
 func (f FolderConfiguration) ModTimeWindow() time.Duration {
 	return f.cachedModTimeWindow
 }
@@ -205,8 +219,31 @@ func (f *FolderConfiguration) DeviceIDs() []protocol.DeviceID {
 	return deviceIDs
 }
 
+// SetQuickSync configures the folder to pull the smallest files first with a
+// single copier, which hands the blocks of one file after another to the
+// pullers, so small files tend to complete early on. It doesn't limit pulling
+// to one file at a time though; the pullers still work on the blocks of
+// several files in parallel. Disabling it restores the default order and
+// number of copiers if they have been set up by enabling it and haven't been
+// changed since, so identical settings made manually are kept.
+func (f *FolderConfiguration) SetQuickSync(enabled bool) {
+	switch {
+	case enabled:
+		f.Order = OrderSmallestFirst
+		f.Copiers = 1
+	case f.QuickSync && f.isQuickSync():
+		f.Order = OrderRandom
+		f.Copiers = 0
+	}
+	f.QuickSync = enabled
+}
+
+func (f FolderConfiguration) isQuickSync() bool {
+	return f.Order == OrderSmallestFirst && f.Copiers == 1
+}
+
 func (f *FolderConfiguration) prepare() {
-	f.cachedFilesystem = fs.NewFilesystem(f.FilesystemType, f.Path)
+	f.cachedFilesystem = fs.NewFilesystem(f.FilesystemType, f.Path, f.filesystemOptions()...)
 
 	if f.RescanIntervalS > MaxRescanIntervalS {
 		f.RescanIntervalS = MaxRescanIntervalS
@@ -231,6 +268,9 @@ func (f *FolderConfiguration) prepare() {
 		f.MarkerName = DefaultMarkerName
 	}
 
+	// Changing the order or copiers manually turns quick sync off.
+	f.QuickSync = f.QuickSync && f.isQuickSync()
+
 	switch {
 	case f.RawModTimeWindowS > 0:
 		f.cachedModTimeWindow = time.Duration(f.RawModTimeWindowS) * time.Second
@@ -10,6 +10,8 @@ import (
 	"net/url"
 	"os"
 	"strings"
+
+	"github.com/syncthing/syncthing/lib/rand"
 )
 
 type GUIConfiguration struct {
@@ -25,6 +27,12 @@ type GUIConfiguration struct {
 	Debugging                 bool     `xml:"debugging,attr" json:"debugging"`
 	InsecureSkipHostCheck     bool     `xml:"insecureSkipHostcheck,omitempty" json:"insecureSkipHostcheck"`
 	InsecureAllowFrameLoading bool     `xml:"insecureAllowFrameLoading,omitempty" json:"insecureAllowFrameLoading"`
+
+	// addressOverride and apiKeyOverride take precedence over the
+	// STGUIADDRESS and STGUIAPIKEY environment variables. They are set by
+	// the wrapper and never persisted.
+	addressOverride string
+	apiKeyOverride  string
 }
 
 func (c GUIConfiguration) IsAuthEnabled() bool {
@@ -32,11 +40,29 @@ func (c GUIConfiguration) IsAuthEnabled() bool {
 }
 
 func (c GUIConfiguration) IsOverridden() bool {
-	return os.Getenv("STGUIADDRESS") != ""
+	return c.overriddenAddress() != ""
+}
+
+// overriddenAddress returns the address overriding the configured one, if
+// any.
+func (c GUIConfiguration) overriddenAddress() string {
+	if c.addressOverride != "" {
+		return c.addressOverride
+	}
+	return os.Getenv("STGUIADDRESS")
+}
+
+// overriddenAPIKey returns the API key accepted in addition to the configured
+// one, if any.
+func (c GUIConfiguration) overriddenAPIKey() string {
+	if c.apiKeyOverride != "" {
+		return c.apiKeyOverride
+	}
+	return os.Getenv("STGUIAPIKEY")
 }
 
 func (c GUIConfiguration) Address() string {
-	if override := os.Getenv("STGUIADDRESS"); override != "" {
+	if override := c.overriddenAddress(); override != "" {
 		// This value may be of the form "scheme://address:port" or just
 		// "address:port". We need to chop off the scheme. We try to parse it as
 		// an URL if it contains a slash. If that fails, return it as is and let
@@ -60,7 +86,7 @@ func (c GUIConfiguration) Address() string {
 }
 
 func (c GUIConfiguration) Network() string {
-	if override := os.Getenv("STGUIADDRESS"); strings.Contains(override, "/") {
+	if override := c.overriddenAddress(); strings.Contains(override, "/") {
 		url, err := url.Parse(override)
 		if err != nil {
 			return "tcp"
@@ -76,7 +102,7 @@ func (c GUIConfiguration) Network() string {
 }
 
 func (c GUIConfiguration) UseTLS() bool {
-	if override := os.Getenv("STGUIADDRESS"); override != "" {
+	if override := c.overriddenAddress(); override != "" {
 		if strings.HasPrefix(override, "http") {
 			return strings.HasPrefix(override, "https:")
 		}
@@ -118,12 +144,21 @@ func (c GUIConfiguration) URL() string {
 
 // IsValidAPIKey returns true when the given API key is valid, including both
 // the value in config and any overrides
+// AcceptedAPIKey returns an API key accepted by IsValidAPIKey: the
+// configured one or, if there is none, the overriding one.
+func (c GUIConfiguration) AcceptedAPIKey() string {
+	if c.APIKey != "" {
+		return c.APIKey
+	}
+	return c.overriddenAPIKey()
+}
+
 func (c GUIConfiguration) IsValidAPIKey(apiKey string) bool {
 	switch apiKey {
 	case "":
 		return false
 
-	case c.APIKey, os.Getenv("STGUIAPIKEY"):
+	case c.APIKey, c.overriddenAPIKey():
 		return true
 
 	default:
@@ -131,6 +166,11 @@ func (c GUIConfiguration) IsValidAPIKey(apiKey string) bool {
 	}
 }
 
+// NewAPIKey returns a new random API key.
+func NewAPIKey() string {
+	return rand.String(32)
+}
+
 func (c GUIConfiguration) Copy() GUIConfiguration {
 	return c
 }
@@ -53,6 +53,11 @@ type OptionsConfiguration struct {
 	DefaultFolderPath       string   `xml:"defaultFolderPath" json:"defaultFolderPath" default:"~"`
 	SetLowPriority          bool     `xml:"setLowPriority" json:"setLowPriority" default:"true"`
 	MaxConcurrentScans      int      `xml:"maxConcurrentScans" json:"maxConcurrentScans"`
+	MaxOutstandingRequests  int      `xml:"maxOutstandingRequests" json:"maxOutstandingRequests"`                          // block requests in flight across all folders; 0 for no limit
+	MaxConnections          int      `xml:"maxConnections" json:"maxConnections"`                                          // connections to other devices at the same time; 0 for no limit
+	DefaultCopiers          int      `xml:"defaultCopiers" json:"defaultCopiers"`                                          // for folders with copiers unset; 0 for automatic
+	DefaultHashers          int      `xml:"defaultHashers" json:"defaultHashers"`                                          // for folders with hashers unset; 0 for automatic
+	DefaultPullers          int      `xml:"defaultPullers" json:"defaultPullers"`                                          // block requests in flight per folder; 0 for no limit besides pullerMaxPendingKiB
 	CRURL                   string   `xml:"crashReportingURL" json:"crURL" default:"https://crash.syncthing.net/newcrash"` // crash reporting URL
 	CREnabled               bool     `xml:"crashReportingEnabled" json:"crashReportingEnabled" default:"true" restart:"true"`
 	StunKeepaliveStartS     int      `xml:"stunKeepaliveStartS" json:"stunKeepaliveStartS" default:"180"` // 0 for off
@@ -64,6 +64,7 @@ type Wrapper interface {
 
 	GUI() GUIConfiguration
 	SetGUI(gui GUIConfiguration) (Waiter, error)
+	SetGUIOverrides(address, apiKey string)
 	LDAP() LDAPConfiguration
 
 	Options() OptionsConfiguration
@@ -100,6 +101,9 @@ type wrapper struct {
 	subs      []Committer
 	mut       sync.Mutex
 
+	guiAddressOverride string
+	guiAPIKeyOverride  string
+
 	requiresRestart uint32 // an atomic bool
 }
 
@@ -350,7 +354,22 @@ func (w *wrapper) LDAP() LDAPConfiguration {
 func (w *wrapper) GUI() GUIConfiguration {
 	w.mut.Lock()
 	defer w.mut.Unlock()
-	return w.cfg.GUI.Copy()
+	gui := w.cfg.GUI.Copy()
+	gui.addressOverride = w.guiAddressOverride
+	gui.apiKeyOverride = w.guiAPIKeyOverride
+	return gui
+}
+
+// SetGUIOverrides sets the address the GUI listens on instead of the
+// configured one and an API key accepted in addition to the configured one,
+// like the STGUIADDRESS and STGUIAPIKEY environment variables do but only
+// for this config. Empty values fall back to the environment variables. The
+// overrides are never persisted; they apply when the GUI is started next.
+func (w *wrapper) SetGUIOverrides(address, apiKey string) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	w.guiAddressOverride = address
+	w.guiAPIKeyOverride = apiKey
 }
 
 // SetGUI replaces the current GUI configuration object.
@@ -359,6 +378,9 @@ func (w *wrapper) SetGUI(gui GUIConfiguration) (Waiter, error) {
 	defer w.mut.Unlock()
 	newCfg := w.cfg.Copy()
 	newCfg.GUI = gui.Copy()
+	// The overrides handed out by GUI() must not end up in the config.
+	newCfg.GUI.addressOverride = ""
+	newCfg.GUI.apiKeyOverride = ""
 	return w.replaceLocked(newCfg)
 }
 
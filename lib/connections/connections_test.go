@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/protocol"
 )
 
@@ -167,3 +168,42 @@ func TestGetDialer(t *testing.T) {
 		}
 	}
 }
+
+// connCountingModel is a Model reporting a fixed number of connections; the
+// other methods must not be called.
+type connCountingModel struct {
+	Model
+	connections int
+}
+
+func (m connCountingModel) NumConnections() int {
+	return m.connections
+}
+
+func TestConnectionLimitReached(t *testing.T) {
+	cases := []struct {
+		maxConnections int
+		connections    int
+		reached        bool
+	}{
+		{0, 0, false},
+		{0, 500, false},
+		{-1, 500, false},
+		{2, 0, false},
+		{2, 1, false},
+		{2, 2, true},
+		{2, 3, true},
+	}
+
+	for _, tc := range cases {
+		cfg := config.New(protocol.LocalDeviceID)
+		cfg.Options.MaxConnections = tc.maxConnections
+		s := &service{
+			cfg:   config.Wrap("/dev/null", cfg, events.NoopLogger),
+			model: connCountingModel{connections: tc.connections},
+		}
+		if reached := s.connectionLimitReached(); reached != tc.reached {
+			t.Errorf("%d of at most %d connections: expected reached to be %v", tc.connections, tc.maxConnections, tc.reached)
+		}
+	}
+}
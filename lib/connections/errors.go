@@ -0,0 +1,103 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+
+	"github.com/pkg/errors"
+)
+
+// The classes of connection errors as reported by ConnectionFailed events.
+const (
+	ConnectionErrorTLSHandshake     = "tls-handshake"
+	ConnectionErrorDeviceIDMismatch = "device-id-mismatch"
+	ConnectionErrorRefused          = "refused"
+	ConnectionErrorTimeout          = "timeout"
+	ConnectionErrorRelay            = "relay-error"
+	ConnectionErrorProtocol         = "protocol-error"
+	ConnectionErrorOther            = "other"
+)
+
+// wsaeconnrefused is the Windows equivalent of ECONNREFUSED.
+const wsaeconnrefused = syscall.Errno(10061)
+
+// A tlsHandshakeError is returned when the TLS handshake with the other side
+// fails.
+type tlsHandshakeError struct {
+	err error
+}
+
+func (e *tlsHandshakeError) Error() string {
+	return e.err.Error()
+}
+
+// A deviceIDMismatchError is returned when the other side presents a
+// certificate for another device than the expected one. Both IDs are the
+// same if we connected to ourselves.
+type deviceIDMismatchError struct {
+	expected, got protocol.DeviceID
+}
+
+func (e *deviceIDMismatchError) Error() string {
+	if e.got == e.expected {
+		return "connected to self"
+	}
+	return fmt.Sprintf("unexpected device id, expected %s got %s", e.expected, e.got)
+}
+
+// classifyConnectionError returns the class of the given error which
+// occurred while establishing a connection, possibly via a relay.
+func classifyConnectionError(err error, viaRelay bool) string {
+	cause := errors.Cause(err)
+	switch cause.(type) {
+	case *tlsHandshakeError:
+		return ConnectionErrorTLSHandshake
+	case *deviceIDMismatchError:
+		return ConnectionErrorDeviceIDMismatch
+	}
+	switch {
+	case protocol.IsVersionMismatch(err):
+		return ConnectionErrorProtocol
+	case isTimeout(cause):
+		return ConnectionErrorTimeout
+	case isConnectionRefused(cause):
+		return ConnectionErrorRefused
+	case viaRelay:
+		return ConnectionErrorRelay
+	}
+	return ConnectionErrorOther
+}
+
+func isTimeout(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+func isConnectionRefused(err error) bool {
+	for {
+		switch e := err.(type) {
+		case *net.OpError:
+			err = e.Err
+		case *os.SyscallError:
+			err = e.Err
+		case syscall.Errno:
+			return e == syscall.ECONNREFUSED || e == wsaeconnrefused
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+
+	"github.com/pkg/errors"
+)
+
+func TestClassifyConnectionError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	_, refused := net.Dial("tcp", addr)
+	if refused == nil {
+		t.Fatal("Connected to closed listener")
+	}
+
+	cases := []struct {
+		err      error
+		viaRelay bool
+		class    string
+	}{
+		{refused, false, ConnectionErrorRefused},
+		{context.DeadlineExceeded, false, ConnectionErrorTimeout},
+		{&tlsHandshakeError{tls.RecordHeaderError{}}, true, ConnectionErrorTLSHandshake},
+		{errors.Wrap(&deviceIDMismatchError{}, "dialing"), false, ConnectionErrorDeviceIDMismatch},
+		{protocol.ErrTooOldVersion, false, ConnectionErrorProtocol},
+		{errors.New("no invitation"), true, ConnectionErrorRelay},
+		{errors.New("something else"), false, ConnectionErrorOther},
+	}
+	for _, tc := range cases {
+		if class := classifyConnectionError(tc.err, tc.viaRelay); class != tc.class {
+			t.Errorf("Error %q classified as %s, expected %s", tc.err, class, tc.class)
+		}
+	}
+}
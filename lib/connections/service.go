@@ -101,6 +101,7 @@ type ListenerStatusEntry struct {
 	Error        *string  `json:"error"`
 	LANAddresses []string `json:"lanAddresses"`
 	WANAddresses []string `json:"wanAddresses"`
+	NATType      string   `json:"natType"` // as detected via STUN, "unknown" if not applicable
 }
 
 type ConnectionStatusEntry struct {
@@ -224,6 +225,7 @@ func (s *service) handle(ctx context.Context) {
 		certs := cs.PeerCertificates
 		if cl := len(certs); cl != 1 {
 			l.Infof("Got peer certificate list of length %d != 1 from peer at %s; protocol error", cl, c)
+			s.connectionFailed(protocol.EmptyDeviceID, c.RemoteAddr().String(), ConnectionErrorProtocol, fmt.Errorf("expected 1 certificate, got %d", cl))
 			c.Close()
 			continue
 		}
@@ -235,6 +237,7 @@ func (s *service) handle(ctx context.Context) {
 		// clients between the same NAT gateway, and global discovery.
 		if remoteID == s.myID {
 			l.Infof("Connected to myself (%s) at %s - should not happen", remoteID, c)
+			s.connectionFailed(remoteID, c.RemoteAddr().String(), ConnectionErrorDeviceIDMismatch, &deviceIDMismatchError{expected: remoteID, got: remoteID})
 			c.Close()
 			continue
 		}
@@ -260,6 +263,7 @@ func (s *service) handle(ctx context.Context) {
 				// It's something else - connection reset or whatever
 				l.Infof("Failed to exchange Hello messages with %s at %s: %s", remoteID, c, err)
 			}
+			s.connectionFailed(remoteID, c.RemoteAddr().String(), classifyConnectionError(err, c.connType.Transport() == "relay"), err)
 			c.Close()
 			continue
 		}
@@ -292,6 +296,12 @@ func (s *service) handle(ctx context.Context) {
 			continue
 		}
 
+		if !connected && s.connectionLimitReached() {
+			l.Infof("Connection from %s at %s (%s) rejected: limit of %d connections reached", remoteID, c.RemoteAddr(), c.Type(), s.cfg.Options().MaxConnections)
+			c.Close()
+			continue
+		}
+
 		deviceCfg, ok := s.cfg.Device(remoteID)
 		if !ok {
 			l.Infof("Device %s removed from config during connection attempt at %s", remoteID, c)
@@ -311,6 +321,7 @@ func (s *service) handle(ctx context.Context) {
 			// likely wants to know about, since it's an advanced
 			// config. Warn instead of Info.
 			l.Warnf("Bad certificate from %s at %s: %v", remoteID, c, err)
+			s.connectionFailed(remoteID, c.RemoteAddr().String(), ConnectionErrorTLSHandshake, err)
 			c.Close()
 			continue
 		}
@@ -376,6 +387,12 @@ func (s *service) connect(ctx context.Context) {
 				continue
 			}
 
+			if !connected && s.connectionLimitReached() {
+				// The connection would be rejected anyway.
+				l.Debugln("Not dialing", deviceID, "as the connection limit is reached")
+				continue
+			}
+
 			var addrs []string
 			for _, addr := range deviceCfg.Addresses {
 				if addr == "dynamic" {
@@ -493,6 +510,29 @@ func (s *service) connect(ctx context.Context) {
 	}
 }
 
+// connectionFailed emits a ConnectionFailed event for a connection to or
+// from the given device at the given address which couldn't be established.
+// The device ID is empty if it is unknown.
+func (s *service) connectionFailed(deviceID protocol.DeviceID, address, class string, err error) {
+	device := ""
+	if deviceID != protocol.EmptyDeviceID {
+		device = deviceID.String()
+	}
+	s.evLogger.Log(events.ConnectionFailed, map[string]string{
+		"device":  device,
+		"address": address,
+		"class":   class,
+		"error":   err.Error(),
+	})
+}
+
+// connectionLimitReached returns whether no further devices may be
+// connected because the configured maximum number of connections is reached.
+func (s *service) connectionLimitReached() bool {
+	limit := s.cfg.Options().MaxConnections
+	return limit > 0 && s.model.NumConnections() >= limit
+}
+
 func (s *service) isLANHost(host string) bool {
 	// Probably we are called with an ip:port combo which we can resolve as
 	// a TCP address.
@@ -689,6 +729,7 @@ func (s *service) ListenerStatus() map[string]ListenerStatusEntry {
 
 		status.LANAddresses = urlsToStrings(listener.LANAddresses())
 		status.WANAddresses = urlsToStrings(listener.WANAddresses())
+		status.NATType = listener.NATType()
 
 		result[addr] = status
 	}
@@ -805,7 +846,10 @@ func warningFor(dev protocol.DeviceID, msg string) {
 func tlsTimedHandshake(tc *tls.Conn) error {
 	tc.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
 	defer tc.SetDeadline(time.Time{})
-	return tc.Handshake()
+	if err := tc.Handshake(); err != nil {
+		return &tlsHandshakeError{err}
+	}
+	return nil
 }
 
 // IsAllowedNetwork returns true if the given host (IP or resolvable
@@ -869,6 +913,9 @@ func (s *service) dialParallel(ctx context.Context, deviceID protocol.DeviceID,
 				s.setConnectionStatus(tgt.addr, err)
 				if err != nil {
 					l.Debugln("dialing", deviceID, tgt.uri, "error:", err)
+					if errors.Cause(err) != context.Canceled {
+						s.connectionFailed(deviceID, tgt.addr, classifyConnectionError(err, tgt.uri.Scheme == "relay"), err)
+					}
 				} else {
 					l.Debugln("dialing", deviceID, tgt.uri, "success:", conn)
 					res <- conn
@@ -925,13 +972,13 @@ func (s *service) validateIdentity(c internalConn, expectedID protocol.DeviceID)
 	if remoteID == s.myID {
 		l.Infof("Connected to myself (%s) at %s - should not happen", remoteID, c)
 		c.Close()
-		return fmt.Errorf("connected to self")
+		return &deviceIDMismatchError{expected: remoteID, got: remoteID}
 	}
 
 	// We should see the expected device ID
 	if !remoteID.Equals(expectedID) {
 		c.Close()
-		return fmt.Errorf("unexpected device id, expected %s got %s", expectedID, remoteID)
+		return &deviceIDMismatchError{expected: expectedID, got: remoteID}
 	}
 
 	return nil
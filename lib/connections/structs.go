@@ -198,6 +198,7 @@ type Model interface {
 	protocol.Model
 	AddConnection(conn Connection, hello protocol.HelloResult)
 	Connection(remoteID protocol.DeviceID) (Connection, bool)
+	NumConnections() int
 	OnHello(protocol.DeviceID, net.Addr, protocol.HelloResult) error
 	GetHello(protocol.DeviceID) protocol.HelloIntf
 }
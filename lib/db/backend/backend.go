@@ -99,6 +99,9 @@ type Backend interface {
 	Writer
 	NewReadTransaction() (ReadTransaction, error)
 	NewWriteTransaction() (WriteTransaction, error)
+	// Compact compacts the whole database, reclaiming the space of deleted
+	// and overwritten entries. This may take a long time on large databases.
+	Compact() error
 	Close() error
 }
 
@@ -111,10 +114,55 @@ const (
 	TuningLarge
 )
 
+// RepairStage describes a step of the repair that is performed when a
+// database is found to be corrupted while opening it.
+type RepairStage int
+
+const (
+	// RepairStarted signals that corruption was detected and recovery is
+	// being attempted. This may take a long time on large databases; there
+	// is no progress until the next stage as the recovery doesn't report any.
+	RepairStarted RepairStage = iota
+	// RepairReinitializing signals that recovery was not possible and the
+	// database is being dropped and created from scratch.
+	RepairReinitializing
+	// RepairFinished signals that the database could be opened after the
+	// repair.
+	RepairFinished
+	// RepairFailed signals that the database couldn't be opened even after
+	// attempting the repair.
+	RepairFailed
+)
+
+func (s RepairStage) String() string {
+	switch s {
+	case RepairStarted:
+		return "started"
+	case RepairReinitializing:
+		return "reinitializing"
+	case RepairFinished:
+		return "finished"
+	case RepairFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// A RepairHandler is informed about the stages of a database repair.
+type RepairHandler func(stage RepairStage, location string)
+
 func Open(path string, tuning Tuning) (Backend, error) {
 	return OpenLevelDB(path, tuning)
 }
 
+// OpenWithRepairHandler is like Open but calls the given handler when the
+// database needs to be repaired. The handler is not called at all when the
+// database can be opened right away.
+func OpenWithRepairHandler(path string, tuning Tuning, handler RepairHandler) (Backend, error) {
+	return OpenLevelDBWithRepairHandler(path, tuning, handler)
+}
+
 func OpenMemory() Backend {
 	return OpenLevelDBMemory()
 }
@@ -15,6 +15,7 @@ func testBackendBehavior(t *testing.T, open func() Backend) {
 	t.Run("WriteIsolation", func(t *testing.T) { testWriteIsolation(t, open) })
 	t.Run("DeleteNonexisten", func(t *testing.T) { testDeleteNonexistent(t, open) })
 	t.Run("IteratorClosedDB", func(t *testing.T) { testIteratorClosedDB(t, open) })
+	t.Run("Compact", func(t *testing.T) { testCompact(t, open) })
 }
 
 func testWriteIsolation(t *testing.T, open func() Backend) {
@@ -53,6 +54,28 @@ func testDeleteNonexistent(t *testing.T, open func() Backend) {
 	}
 }
 
+func testCompact(t *testing.T, open func() Backend) {
+	// Compacting keeps the current values and drops deleted ones
+
+	db := open()
+	defer db.Close()
+
+	_ = db.Put([]byte("a"), []byte("a"))
+	_ = db.Put([]byte("b"), []byte("b"))
+	_ = db.Put([]byte("a"), []byte("c"))
+	_ = db.Delete([]byte("b"))
+
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.Get([]byte("a")); err != nil || string(v) != "c" {
+		t.Errorf("read back after compaction returned %q, %v", v, err)
+	}
+	if _, err := db.Get([]byte("b")); !IsNotFound(err) {
+		t.Errorf("deleted key readable after compaction: %v", err)
+	}
+}
+
 // Either creating the iterator or the .Error() method of the returned iterator
 // should return an error and IsClosed(err) == true.
 func testIteratorClosedDB(t *testing.T, open func() Backend) {
@@ -60,6 +60,10 @@ func (b *leveldbBackend) Close() error {
 	return wrapLeveldbErr(b.ldb.Close())
 }
 
+func (b *leveldbBackend) Compact() error {
+	return wrapLeveldbErr(b.ldb.CompactRange(util.Range{}))
+}
+
 func (b *leveldbBackend) Get(key []byte) ([]byte, error) {
 	val, err := b.ldb.Get(key, nil)
 	return val, wrapLeveldbErr(err)
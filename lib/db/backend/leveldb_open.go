@@ -37,8 +37,14 @@ const (
 // recovery on it if opening fails. Worst case, if recovery is not possible,
 // the database is erased and created from scratch.
 func OpenLevelDB(location string, tuning Tuning) (Backend, error) {
+	return OpenLevelDBWithRepairHandler(location, tuning, nil)
+}
+
+// OpenLevelDBWithRepairHandler is like OpenLevelDB but informs the given
+// handler, if not nil, about the stages of the recovery.
+func OpenLevelDBWithRepairHandler(location string, tuning Tuning, handler RepairHandler) (Backend, error) {
 	opts := optsFor(location, tuning)
-	ldb, err := open(location, opts)
+	ldb, err := open(location, opts, handler)
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +57,7 @@ func OpenLevelDBRO(location string) (Backend, error) {
 		OpenFilesCacheCapacity: dbMaxOpenFiles,
 		ReadOnly:               true,
 	}
-	ldb, err := open(location, opts)
+	ldb, err := open(location, opts, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -132,9 +138,18 @@ func optsFor(location string, tuning Tuning) *opt.Options {
 	return opts
 }
 
-func open(location string, opts *opt.Options) (*leveldb.DB, error) {
+func open(location string, opts *opt.Options, handler RepairHandler) (*leveldb.DB, error) {
+	notify := func(stage RepairStage) {
+		if handler != nil {
+			handler(stage, location)
+		}
+	}
+
 	db, err := leveldb.OpenFile(location, opts)
-	if leveldbIsCorrupted(err) {
+	repairing := leveldbIsCorrupted(err)
+	if repairing {
+		l.Infoln("Database corruption detected, attempting recovery...")
+		notify(RepairStarted)
 		db, err = leveldb.RecoverFile(location, opts)
 	}
 	if leveldbIsCorrupted(err) {
@@ -142,14 +157,22 @@ func open(location string, opts *opt.Options) (*leveldb.DB, error) {
 		// didn't work. At this point there isn't much to do beyond dropping
 		// the database and reindexing...
 		l.Infoln("Database corruption detected, unable to recover. Reinitializing...")
+		notify(RepairReinitializing)
 		if err := os.RemoveAll(location); err != nil {
+			notify(RepairFailed)
 			return nil, errorSuggestion{err, "failed to delete corrupted database"}
 		}
 		db, err = leveldb.OpenFile(location, opts)
 	}
 	if err != nil {
+		if repairing {
+			notify(RepairFailed)
+		}
 		return nil, errorSuggestion{err, "is another instance of Syncthing running?"}
 	}
+	if repairing {
+		notify(RepairFinished)
+	}
 
 	if debugEnvValue("CompactEverything", 0) != 0 {
 		if err := db.CompactRange(util.Range{}); err != nil {
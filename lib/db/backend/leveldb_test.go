@@ -6,8 +6,63 @@
 
 package backend
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestLevelDBBackendBehavior(t *testing.T) {
 	testBackendBehavior(t, OpenLevelDBMemory)
 }
+
+func TestLevelDBRepairHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-leveldb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	location := filepath.Join(dir, "index")
+
+	var stages []RepairStage
+	handler := func(stage RepairStage, loc string) {
+		if loc != location {
+			t.Errorf("unexpected location %q", loc)
+		}
+		stages = append(stages, stage)
+	}
+
+	// Opening a healthy database must not involve the handler.
+	db, err := OpenLevelDBWithRepairHandler(location, TuningAuto, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put([]byte("a"), []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if len(stages) != 0 {
+		t.Fatal("unexpected repair stages for healthy database:", stages)
+	}
+
+	// Corrupt the manifest so opening requires a recovery.
+	manifests, err := filepath.Glob(filepath.Join(location, "MANIFEST-*"))
+	if err != nil || len(manifests) == 0 {
+		t.Fatal("no manifest found:", err)
+	}
+	for _, m := range manifests {
+		if err := ioutil.WriteFile(m, []byte("garbage"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err = OpenLevelDBWithRepairHandler(location, TuningAuto, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if len(stages) < 2 || stages[0] != RepairStarted || stages[len(stages)-1] != RepairFinished {
+		t.Fatal("unexpected repair stages:", stages)
+	}
+}
@@ -27,6 +27,8 @@ type CachingMux interface {
 	FinderService
 	Add(finder Finder, cacheTime, negCacheTime time.Duration)
 	ChildErrors() map[string]error
+	SetCacheTime(cacheTime time.Duration)
+	ClearCache()
 }
 
 type cachingMux struct {
@@ -41,6 +43,7 @@ type cachedFinder struct {
 	Finder
 	cacheTime    time.Duration
 	negCacheTime time.Duration
+	cached       bool // whether it was added with a positive cache time
 }
 
 // An error may implement cachedError, in which case it will be interrogated
@@ -62,7 +65,7 @@ func NewCachingMux() CachingMux {
 // Add registers a new Finder, with associated cache timeouts.
 func (m *cachingMux) Add(finder Finder, cacheTime, negCacheTime time.Duration) {
 	m.mut.Lock()
-	m.finders = append(m.finders, cachedFinder{finder, cacheTime, negCacheTime})
+	m.finders = append(m.finders, cachedFinder{finder, cacheTime, negCacheTime, cacheTime > 0})
 	m.caches = append(m.caches, newCache())
 	m.mut.Unlock()
 
@@ -149,6 +152,27 @@ func (m *cachingMux) ChildErrors() map[string]error {
 	return children
 }
 
+// SetCacheTime changes the cache time of the Finders which were added with
+// a positive cache time. Finders added without caching are unaffected.
+func (m *cachingMux) SetCacheTime(cacheTime time.Duration) {
+	m.mut.Lock()
+	for i := range m.finders {
+		if m.finders[i].cached {
+			m.finders[i].cacheTime = cacheTime
+		}
+	}
+	m.mut.Unlock()
+}
+
+// ClearCache removes all positive and negative cache entries.
+func (m *cachingMux) ClearCache() {
+	m.mut.Lock()
+	for i := range m.caches {
+		m.caches[i] = newCache()
+	}
+	m.mut.Unlock()
+}
+
 func (m *cachingMux) Cache() map[protocol.DeviceID]CacheEntry {
 	// Res will be the "total" cache, i.e. the union of our cache and all our
 	// children's caches.
@@ -62,6 +62,45 @@ func TestCacheUnique(t *testing.T) {
 	}
 }
 
+func TestCacheClearAndCacheTime(t *testing.T) {
+	c := NewCachingMux()
+	c.(*cachingMux).ServeBackground()
+	defer c.Stop()
+
+	f := &fakeDiscovery{[]string{"tcp://192.0.2.42:22000"}}
+	c.Add(f, time.Minute, 0)
+
+	lookup := func() []string {
+		t.Helper()
+		addr, err := c.Lookup(protocol.LocalDeviceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr
+	}
+
+	lookup()
+	f.addresses = []string{"tcp://192.0.2.43:22000"}
+
+	// The cached result is returned until the cache is cleared.
+
+	if addr := lookup(); !reflect.DeepEqual(addr, []string{"tcp://192.0.2.42:22000"}) {
+		t.Errorf("Incorrect cached addresses; %+v", addr)
+	}
+	c.ClearCache()
+	if addr := lookup(); !reflect.DeepEqual(addr, f.addresses) {
+		t.Errorf("Incorrect addresses after clearing cache; %+v != %+v", addr, f.addresses)
+	}
+
+	// With a cache time of zero results are not cached anymore.
+
+	c.SetCacheTime(0)
+	f.addresses = []string{"tcp://192.0.2.44:22000"}
+	if addr := lookup(); !reflect.DeepEqual(addr, f.addresses) {
+		t.Errorf("Incorrect addresses without caching; %+v != %+v", addr, f.addresses)
+	}
+}
+
 type fakeDiscovery struct {
 	addresses []string
 }
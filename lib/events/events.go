@@ -52,6 +52,7 @@ const (
 	FolderWatchStateChanged
 	ListenAddressesChanged
 	LoginAttempt
+	ConnectionFailed
 
 	AllEvents = (1 << iota) - 1
 )
@@ -119,6 +120,8 @@ func (t EventType) String() string {
 		return "ListenAddressesChanged"
 	case LoginAttempt:
 		return "LoginAttempt"
+	case ConnectionFailed:
+		return "ConnectionFailed"
 	case FolderWatchStateChanged:
 		return "FolderWatchStateChanged"
 	default:
@@ -198,6 +201,8 @@ func UnmarshalEventType(s string) EventType {
 		return ListenAddressesChanged
 	case "LoginAttempt":
 		return LoginAttempt
+	case "ConnectionFailed":
+		return ConnectionFailed
 	case "FolderWatchStateChanged":
 		return FolderWatchStateChanged
 	default:
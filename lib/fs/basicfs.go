@@ -26,7 +26,20 @@ var (
 // The BasicFilesystem implements all aspects by delegating to package os.
 // All paths are relative to the root and cannot (should not) escape the root directory.
 type BasicFilesystem struct {
-	root string
+	root            string
+	junctionsAsDirs bool
+}
+
+// WithJunctionsAsDirs makes a basic filesystem follow directory junctions on
+// Windows, so they appear as the directories they point to. Otherwise they
+// are treated like symlinks, which are not supported on Windows. It has no
+// effect on other filesystems and platforms.
+func WithJunctionsAsDirs() Option {
+	return func(fs Filesystem) {
+		if basic, ok := fs.(*BasicFilesystem); ok {
+			basic.junctionsAsDirs = true
+		}
+	}
 }
 
 func newBasicFilesystem(root string) *BasicFilesystem {
@@ -60,7 +73,7 @@ func newBasicFilesystem(root string) *BasicFilesystem {
 		root = longFilenameSupport(root)
 	}
 
-	return &BasicFilesystem{root}
+	return &BasicFilesystem{root: root}
 }
 
 // rooted expands the relative path to the full path that is then used with os
@@ -145,6 +158,11 @@ func (f *BasicFilesystem) Lstat(name string) (FileInfo, error) {
 	if err != nil {
 		return nil, err
 	}
+	if f.junctionsAsDirs && fi.Mode()&(os.ModeSymlink|os.ModeIrregular) != 0 && isDirectoryJunction(name) {
+		if fi, err = os.Stat(name); err != nil {
+			return nil, err
+		}
+	}
 	return basicFileInfo{fi}, err
 }
 
@@ -34,6 +34,12 @@ func (f *BasicFilesystem) ReadSymlink(name string) (string, error) {
 	return os.Readlink(name)
 }
 
+// isDirectoryJunction returns whether the given path is a directory junction,
+// which only exist on Windows.
+func isDirectoryJunction(path string) bool {
+	return false
+}
+
 func (f *BasicFilesystem) mkdirAll(path string, perm os.FileMode) error {
 	return os.MkdirAll(path, perm)
 }
@@ -33,6 +33,27 @@ func (BasicFilesystem) CreateSymlink(target, name string) error {
 	return errNotSupported
 }
 
+// ioReparseTagMountPoint is the reparse tag of directory junctions
+// (IO_REPARSE_TAG_MOUNT_POINT).
+const ioReparseTagMountPoint = 0xA0000003
+
+// isDirectoryJunction returns whether the given path is a directory junction
+// rather than a symlink or another kind of reparse point.
+func isDirectoryJunction(path string) bool {
+	namep, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return false
+	}
+	var data syscall.Win32finddata
+	h, err := syscall.FindFirstFile(namep, &data)
+	if err != nil {
+		return false
+	}
+	syscall.FindClose(h)
+	// For reparse points Reserved0 contains the reparse tag.
+	return data.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0 && data.Reserved0 == ioReparseTagMountPoint
+}
+
 // Required due to https://github.com/golang/go/issues/10900
 func (f *BasicFilesystem) mkdirAll(path string, perm os.FileMode) error {
 	// Fast path: if we can tell whether path is a directory or file, stop with success or error.
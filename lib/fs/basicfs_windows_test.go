@@ -10,6 +10,7 @@ package fs
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -193,3 +194,30 @@ func TestGetFinalPath(t *testing.T) {
 		}
 	}
 }
+
+func TestJunctionsAsDirs(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	if err := fs.Mkdir("target", 0755); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "target")
+	junction := filepath.Join(dir, "junction")
+	if out, err := exec.Command("cmd", "/c", "mklink", "/J", junction, target).CombinedOutput(); err != nil {
+		t.Skipf("creating junction: %v: %s", err, out)
+	}
+
+	if info, err := fs.Lstat("junction"); err != nil {
+		t.Fatal(err)
+	} else if info.IsDir() {
+		t.Error("junction is a directory without WithJunctionsAsDirs")
+	}
+
+	WithJunctionsAsDirs()(fs)
+	if info, err := fs.Lstat("junction"); err != nil {
+		t.Fatal(err)
+	} else if !info.IsDir() {
+		t.Error("junction is not a directory with WithJunctionsAsDirs")
+	}
+}
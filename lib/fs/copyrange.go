@@ -0,0 +1,187 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// CopyRangeMethod is the way data is copied from one file to another by
+// CopyRange.
+type CopyRangeMethod int
+
+const (
+	// CopyRangeMethodStandard reads the data and writes it to the
+	// destination.
+	CopyRangeMethodStandard CopyRangeMethod = iota
+	// CopyRangeMethodIoctl clones the data using the FICLONERANGE ioctl
+	// (Linux, e.g. on btrfs and XFS), so it is shared rather than copied.
+	CopyRangeMethodIoctl
+	// CopyRangeMethodCopyFileRange uses the copy_file_range syscall (Linux),
+	// which may clone the data or copy it within the kernel.
+	CopyRangeMethodCopyFileRange
+	// CopyRangeMethodSendFile uses the sendfile syscall (Linux) to copy the
+	// data within the kernel.
+	CopyRangeMethodSendFile
+	// CopyRangeMethodAllWithFallback tries the methods supported on the
+	// platform in turn, falling back to the standard method.
+	CopyRangeMethodAllWithFallback
+)
+
+var errCopyRangeNotSupported = errors.New("copy range method not supported")
+
+// copyRangeImplementation copies size bytes from src at srcOffset to dst at
+// dstOffset.
+type copyRangeImplementation func(src, dst File, srcOffset, dstOffset, size int64) error
+
+// copyRangeImplementations contains the methods supported on the current
+// platform, extended by the platform specific files.
+var copyRangeImplementations = map[CopyRangeMethod]copyRangeImplementation{
+	CopyRangeMethodStandard: copyRangeStandard,
+}
+
+func (m CopyRangeMethod) String() string {
+	switch m {
+	case CopyRangeMethodStandard:
+		return "standard"
+	case CopyRangeMethodIoctl:
+		return "ioctl"
+	case CopyRangeMethodCopyFileRange:
+		return "copy_file_range"
+	case CopyRangeMethodSendFile:
+		return "sendfile"
+	case CopyRangeMethodAllWithFallback:
+		return "all"
+	default:
+		return "unknown"
+	}
+}
+
+func (m CopyRangeMethod) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *CopyRangeMethod) UnmarshalText(bs []byte) error {
+	method, err := ParseCopyRangeMethod(string(bs))
+	if err != nil {
+		method = CopyRangeMethodStandard
+	}
+	*m = method
+	return nil
+}
+
+// ParseCopyRangeMethod returns the method with the given name, regardless of
+// whether it is supported on the current platform.
+func ParseCopyRangeMethod(name string) (CopyRangeMethod, error) {
+	for m := CopyRangeMethodStandard; m <= CopyRangeMethodAllWithFallback; m++ {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return CopyRangeMethodStandard, fmt.Errorf("unknown copy range method %q", name)
+}
+
+// Supported returns whether the method is supported on the current platform.
+func (m CopyRangeMethod) Supported() bool {
+	if m == CopyRangeMethodAllWithFallback {
+		return true
+	}
+	_, ok := copyRangeImplementations[m]
+	return ok
+}
+
+// SupportedCopyRangeMethods returns the methods supported on the current
+// platform.
+func SupportedCopyRangeMethods() []CopyRangeMethod {
+	methods := []CopyRangeMethod{CopyRangeMethodAllWithFallback}
+	for m := range copyRangeImplementations {
+		methods = append(methods, m)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i] < methods[j] })
+	return methods
+}
+
+// CopyRange copies size bytes from src at srcOffset to dst at dstOffset using
+// the given method. All methods but the standard one require both files to
+// be files of the basic filesystem and may not work across filesystems, or
+// require the offsets to be aligned to the filesystem's block size. The
+// sendfile method changes the file offset of dst.
+func CopyRange(method CopyRangeMethod, src, dst File, srcOffset, dstOffset, size int64) error {
+	if method == CopyRangeMethodAllWithFallback {
+		for _, m := range []CopyRangeMethod{CopyRangeMethodIoctl, CopyRangeMethodCopyFileRange, CopyRangeMethodSendFile} {
+			if impl, ok := copyRangeImplementations[m]; ok && impl(src, dst, srcOffset, dstOffset, size) == nil {
+				return nil
+			}
+		}
+		return copyRangeStandard(src, dst, srcOffset, dstOffset, size)
+	}
+	impl, ok := copyRangeImplementations[method]
+	if !ok {
+		return errCopyRangeNotSupported
+	}
+	return impl(src, dst, srcOffset, dstOffset, size)
+}
+
+func copyRangeStandard(src, dst File, srcOffset, dstOffset, size int64) error {
+	const maxBufSize = 1 << 20
+	bufSize := size
+	if bufSize > maxBufSize {
+		bufSize = maxBufSize
+	}
+	buf := make([]byte, bufSize)
+	for size > 0 {
+		if size < int64(len(buf)) {
+			buf = buf[:size]
+		}
+		n, err := src.ReadAt(buf, srcOffset)
+		if err == io.EOF && n == len(buf) {
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := dst.WriteAt(buf, dstOffset); err != nil {
+			return err
+		}
+		srcOffset += int64(n)
+		dstOffset += int64(n)
+		size -= int64(n)
+	}
+	return nil
+}
+
+// fdFile is implemented by the files of the basic filesystem.
+type fdFile interface {
+	Fd() uintptr
+}
+
+// fileDescriptors returns the file descriptors underlying the given files
+// if both are (possibly wrapped) files of the basic filesystem.
+func fileDescriptors(src, dst File) (uintptr, uintptr, error) {
+	srcFd, ok := unwrapFile(src).(fdFile)
+	if !ok {
+		return 0, 0, errCopyRangeNotSupported
+	}
+	dstFd, ok := unwrapFile(dst).(fdFile)
+	if !ok {
+		return 0, 0, errCopyRangeNotSupported
+	}
+	return srcFd.Fd(), dstFd.Fd(), nil
+}
+
+func unwrapFile(file File) File {
+	for {
+		wrapped, ok := file.(*mtimeFile)
+		if !ok {
+			return file
+		}
+		file = wrapped.File
+	}
+}
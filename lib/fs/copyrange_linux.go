@@ -0,0 +1,110 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux
+
+package fs
+
+import (
+	"io"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func init() {
+	copyRangeImplementations[CopyRangeMethodIoctl] = copyRangeIoctl
+	copyRangeImplementations[CopyRangeMethodCopyFileRange] = copyRangeCopyFileRange
+	copyRangeImplementations[CopyRangeMethodSendFile] = copyRangeSendFile
+}
+
+// fileCloneRange is struct file_clone_range from linux/fs.h.
+type fileCloneRange struct {
+	srcFd      int64
+	srcOffset  uint64
+	srcLength  uint64
+	destOffset uint64
+}
+
+// ficlonerange returns the FICLONERANGE request, _IOW(0x94, 13, struct
+// file_clone_range), whose direction bits differ between architectures.
+func ficlonerange() uintptr {
+	const request = 0x94<<8 | 13 | uintptr(unsafe.Sizeof(fileCloneRange{}))<<16
+	switch runtime.GOARCH {
+	case "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le":
+		return request | 4<<29
+	default:
+		return request | 1<<30
+	}
+}
+
+func copyRangeIoctl(src, dst File, srcOffset, dstOffset, size int64) error {
+	srcFd, dstFd, err := fileDescriptors(src, dst)
+	if err != nil {
+		return err
+	}
+	params := fileCloneRange{
+		srcFd:      int64(srcFd),
+		srcOffset:  uint64(srcOffset),
+		srcLength:  uint64(size),
+		destOffset: uint64(dstOffset),
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dstFd, ficlonerange(), uintptr(unsafe.Pointer(&params)))
+	runtime.KeepAlive(src)
+	runtime.KeepAlive(dst)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func copyRangeCopyFileRange(src, dst File, srcOffset, dstOffset, size int64) error {
+	srcFd, dstFd, err := fileDescriptors(src, dst)
+	if err != nil {
+		return err
+	}
+	defer runtime.KeepAlive(src)
+	defer runtime.KeepAlive(dst)
+	for size > 0 {
+		// The offsets are advanced by the syscall.
+		n, err := unix.CopyFileRange(int(srcFd), &srcOffset, int(dstFd), &dstOffset, int(size), 0)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrUnexpectedEOF
+		}
+		size -= int64(n)
+	}
+	return nil
+}
+
+func copyRangeSendFile(src, dst File, srcOffset, dstOffset, size int64) error {
+	srcFd, dstFd, err := fileDescriptors(src, dst)
+	if err != nil {
+		return err
+	}
+	defer runtime.KeepAlive(src)
+	defer runtime.KeepAlive(dst)
+	// sendfile always writes at the current offset of the destination.
+	if _, err := dst.Seek(dstOffset, io.SeekStart); err != nil {
+		return err
+	}
+	for size > 0 {
+		// The source offset is advanced by the syscall.
+		n, err := unix.Sendfile(int(dstFd), int(srcFd), &srcOffset, int(size))
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return io.ErrUnexpectedEOF
+		}
+		size -= int64(n)
+	}
+	return nil
+}
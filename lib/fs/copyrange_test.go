@@ -0,0 +1,88 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCopyRangeMethod(t *testing.T) {
+	for m := CopyRangeMethodStandard; m <= CopyRangeMethodAllWithFallback; m++ {
+		parsed, err := ParseCopyRangeMethod(m.String())
+		if err != nil || parsed != m {
+			t.Errorf("ParseCopyRangeMethod(%q) = %v, %v", m.String(), parsed, err)
+		}
+	}
+	if _, err := ParseCopyRangeMethod("reflink"); err == nil {
+		t.Error("Expected error for unknown method")
+	}
+
+	m := CopyRangeMethodSendFile
+	if err := m.UnmarshalText([]byte("bogus")); err != nil || m != CopyRangeMethodStandard {
+		t.Errorf("Unknown method unmarshalled as %v, %v", m, err)
+	}
+
+	if !CopyRangeMethodStandard.Supported() || !CopyRangeMethodAllWithFallback.Supported() {
+		t.Error("Standard and fallback methods must always be supported")
+	}
+}
+
+func TestCopyRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-copyrange-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filesystem := NewMtimeFS(NewFilesystem(FilesystemTypeBasic, dir), make(mapStore))
+
+	const size = 64 << 10
+	data := make([]byte, 2*size)
+	rand.Read(data)
+	if err := ioutil.WriteFile(filepath.Join(dir, "src"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, method := range SupportedCopyRangeMethods() {
+		t.Run(method.String(), func(t *testing.T) {
+			src, err := filesystem.Open("src")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer src.Close()
+			dst, err := filesystem.Create(method.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dst.Close()
+
+			// Copy the second half of the source to the start of the
+			// destination and the first half after it.
+			if err := CopyRange(method, src, dst, size, 0, size); err != nil {
+				if method == CopyRangeMethodIoctl {
+					t.Skip("cloning not supported by the filesystem:", err)
+				}
+				t.Fatal(err)
+			}
+			if err := CopyRange(method, src, dst, 0, size, size); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := ioutil.ReadFile(filepath.Join(dir, method.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(res[:size], data[size:]) || !bytes.Equal(res[size:], data[:size]) {
+				t.Error("Copied data doesn't match")
+			}
+		})
+	}
+}
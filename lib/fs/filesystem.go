@@ -172,7 +172,10 @@ var IsPermission = os.IsPermission
 // IsPathSeparator is the equivalent of os.IsPathSeparator
 var IsPathSeparator = os.IsPathSeparator
 
-func NewFilesystem(fsType FilesystemType, uri string) Filesystem {
+// An Option modifies the behaviour of a filesystem created by NewFilesystem.
+type Option func(Filesystem)
+
+func NewFilesystem(fsType FilesystemType, uri string, opts ...Option) Filesystem {
 	var fs Filesystem
 	switch fsType {
 	case FilesystemTypeBasic:
@@ -188,6 +191,10 @@ func NewFilesystem(fsType FilesystemType, uri string) Filesystem {
 		}
 	}
 
+	for _, opt := range opts {
+		opt(fs)
+	}
+
 	if l.ShouldDebug("walkfs") {
 		return NewWalkFilesystem(&logFilesystem{fs})
 	}
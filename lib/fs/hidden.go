@@ -0,0 +1,56 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// systemFiles are the (lower case) names of files and directories created
+// by the operating system which are considered hidden regardless of their
+// attributes.
+var systemFiles = map[string]struct{}{
+	".ds_store":                 {},
+	"thumbs.db":                 {},
+	"ehthumbs.db":               {},
+	"desktop.ini":               {},
+	"$recycle.bin":              {},
+	"system volume information": {},
+}
+
+// IsHidden returns true if the file, as a path relative to the root of the
+// given filesystem, or one of the directories containing it is hidden by the
+// conventions of the operating system or a well known system file. On
+// Windows this checks the hidden and system attributes of the files if they
+// exist within a basic filesystem, elsewhere files whose name starts with a
+// dot are hidden.
+func IsHidden(filesystem Filesystem, name string) bool {
+	for name != "." && name != string(filepath.Separator) && name != "" {
+		if IsHiddenFile(filesystem, name, nil) {
+			return true
+		}
+		parent := filepath.Dir(name)
+		if parent == name {
+			break
+		}
+		name = parent
+	}
+	return false
+}
+
+// IsHiddenFile is like IsHidden, but only checks the file itself and not
+// the directories containing it, e.g. when walking a tree whose hidden
+// directories are skipped anyway. If info is the result of Lstat on the
+// file, its attributes are used instead of looking them up again.
+func IsHiddenFile(filesystem Filesystem, name string, info FileInfo) bool {
+	base := filepath.Base(name)
+	if _, ok := systemFiles[strings.ToLower(base)]; ok {
+		return true
+	}
+	return isHidden(filesystem, name, base, info)
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestIsHidden(t *testing.T) {
+	cases := []struct {
+		name   string
+		hidden bool
+	}{
+		{"foo", false},
+		{"foo/bar.txt", false},
+		{"Thumbs.db", true},
+		{"foo/thumbs.db", true},
+		{"foo/Desktop.ini", true},
+		{".DS_Store", true},
+		{"foo/.DS_Store", true},
+		{"foo.bar/baz", false},
+		{".hidden", runtime.GOOS != "windows"},
+		{"foo/.hidden", runtime.GOOS != "windows"},
+		{".git/config", runtime.GOOS != "windows"},
+		{"foo/.git/objects/ab", runtime.GOOS != "windows"},
+		{"$RECYCLE.BIN/foo", true},
+		{"foo/System Volume Information/bar/baz", true},
+	}
+	for _, tc := range cases {
+		if res := IsHidden(nil, filepath.FromSlash(tc.name)); res != tc.hidden {
+			t.Errorf("IsHidden(%q) = %v, expected %v", tc.name, res, tc.hidden)
+		}
+	}
+
+	// Only the file itself is checked by IsHiddenFile.
+	if IsHiddenFile(nil, filepath.FromSlash("$RECYCLE.BIN/foo"), nil) {
+		t.Error("IsHiddenFile checked the parent directory")
+	}
+	if !IsHiddenFile(nil, filepath.FromSlash("foo/Thumbs.db"), nil) {
+		t.Error("IsHiddenFile didn't detect a system file")
+	}
+}
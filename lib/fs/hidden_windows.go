@@ -0,0 +1,49 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build windows
+
+package fs
+
+import (
+	"path/filepath"
+	"syscall"
+)
+
+func isHidden(filesystem Filesystem, name, _ string, info FileInfo) bool {
+	if filesystem == nil || filesystem.Type() != FilesystemTypeBasic {
+		return false
+	}
+	attrs, ok := fileAttributes(info)
+	if !ok {
+		p, err := syscall.UTF16PtrFromString(filepath.Join(filesystem.URI(), name))
+		if err != nil {
+			return false
+		}
+		if attrs, err = syscall.GetFileAttributes(p); err != nil {
+			return false
+		}
+	}
+	return attrs&(syscall.FILE_ATTRIBUTE_HIDDEN|syscall.FILE_ATTRIBUTE_SYSTEM) != 0
+}
+
+// fileAttributes returns the attributes contained in the given file info if
+// it stems from the basic filesystem, possibly wrapped by the mtime
+// filesystem.
+func fileAttributes(info FileInfo) (uint32, bool) {
+	if mi, ok := info.(mtimeFileInfo); ok {
+		info = mi.FileInfo
+	}
+	bi, ok := info.(basicFileInfo)
+	if !ok {
+		return 0, false
+	}
+	data, ok := bi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return 0, false
+	}
+	return data.FileAttributes, true
+}
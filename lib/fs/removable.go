@@ -0,0 +1,18 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import "errors"
+
+var errRemovableUnknown = errors.New("unable to determine whether the storage is removable")
+
+// IsRemovable returns whether the storage the given absolute path is located
+// on is removable or external, like an SD card or a USB drive. This is a best
+// effort check; an error is returned if it can't be determined.
+func IsRemovable(path string) (bool, error) {
+	return isRemovable(path)
+}
@@ -0,0 +1,69 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux
+
+package fs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+func isRemovable(path string) (bool, error) {
+	if runtime.GOOS == "android" {
+		// SD cards are usually mounted via a FUSE filesystem which has no
+		// block device to check.
+		if removable, ok := isRemovableAndroidPath(path); ok {
+			return removable, nil
+		}
+	}
+
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return false, err
+	}
+	dev := uint64(st.Dev)
+	sysPath, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", unix.Major(dev), unix.Minor(dev)))
+	if err != nil {
+		// Not backed by a block device, e.g. tmpfs or a network filesystem.
+		return false, errRemovableUnknown
+	}
+	if strings.Contains(sysPath, "/usb") {
+		// USB hard drives don't necessarily have the removable flag set.
+		return true, nil
+	}
+	// Partitions don't have a removable flag, their disk (the parent) has.
+	for _, dir := range []string{sysPath, filepath.Dir(sysPath)} {
+		if bs, err := ioutil.ReadFile(filepath.Join(dir, "removable")); err == nil {
+			return strings.TrimSpace(string(bs)) == "1", nil
+		}
+	}
+	return false, errRemovableUnknown
+}
+
+// isRemovableAndroidPath returns whether the given path is on removable
+// storage according to the Android conventions for mount points, and whether
+// the path follows these conventions at all.
+func isRemovableAndroidPath(path string) (removable, ok bool) {
+	path = filepath.Clean(path)
+	for _, internal := range []string{"/storage/emulated", "/storage/self", "/sdcard", "/data"} {
+		if path == internal || IsParent(path, internal) {
+			return false, true
+		}
+	}
+	for _, external := range []string{"/storage", "/mnt/media_rw"} {
+		if IsParent(path, external) {
+			return true, true
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,36 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux
+
+package fs
+
+import "testing"
+
+func TestIsRemovableAndroidPath(t *testing.T) {
+	cases := []struct {
+		path      string
+		removable bool
+		ok        bool
+	}{
+		{"/storage/emulated/0/DCIM", false, true},
+		{"/storage/emulated", false, true},
+		{"/storage/self/primary/Music", false, true},
+		{"/sdcard/Download", false, true},
+		{"/data/data/com.github.catfriend1.syncthingandroid", false, true},
+		{"/storage/1234-5678/Music", true, true},
+		{"/storage/1234-5678", true, true},
+		{"/mnt/media_rw/1234-5678", true, true},
+		{"/storage", false, false},
+		{"/home/user/Sync", false, false},
+	}
+	for _, tc := range cases {
+		removable, ok := isRemovableAndroidPath(tc.path)
+		if removable != tc.removable || ok != tc.ok {
+			t.Errorf("isRemovableAndroidPath(%q) = %v, %v; expected %v, %v", tc.path, removable, ok, tc.removable, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build windows
+
+package fs
+
+import (
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+func isRemovable(path string) (bool, error) {
+	volume := filepath.VolumeName(path)
+	if volume == "" {
+		return false, errRemovableUnknown
+	}
+	root, err := windows.UTF16PtrFromString(volume + `\`)
+	if err != nil {
+		return false, err
+	}
+	switch windows.GetDriveType(root) {
+	case windows.DRIVE_REMOVABLE, windows.DRIVE_CDROM:
+		return true, nil
+	case windows.DRIVE_FIXED, windows.DRIVE_RAMDISK:
+		// External hard drives are reported as fixed as well.
+		return false, nil
+	}
+	// Includes network drives, which may be on any kind of storage.
+	return false, errRemovableUnknown
+}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/syncthing/syncthing/lib/fs"
@@ -43,35 +44,103 @@ const (
 	HomeBaseDir   BaseDirEnum = "home"
 )
 
+// Locations holds a set of base directories and the locations of the files
+// used by Syncthing derived from them. The package level functions operate
+// on the process wide default set; separate sets allow running multiple
+// instances in one process. It is safe for concurrent use.
+type Locations struct {
+	mut       sync.RWMutex
+	baseDirs  map[BaseDirEnum]string
+	locations map[LocationEnum]string
+}
+
+var defaultLocations *Locations
+
 func init() {
-	err := expandLocations()
+	var err error
+	defaultLocations, err = New()
 	if err != nil {
 		fmt.Println(err)
 		panic("Failed to expand locations at init time")
 	}
 }
 
+// New returns a set of locations based on the platform dependent default
+// base directories.
+func New() (*Locations, error) {
+	baseDirs := map[BaseDirEnum]string{
+		ConfigBaseDir: defaultConfigDir(), // Overridden by -home flag
+		HomeBaseDir:   homeDir(),          // User's home directory, *not* -home flag
+	}
+	locations, err := expandLocations(baseDirs)
+	if err != nil {
+		return nil, err
+	}
+	return &Locations{baseDirs: baseDirs, locations: locations}, nil
+}
+
+// Default returns the process wide set of locations.
+func Default() *Locations {
+	return defaultLocations
+}
+
 func SetBaseDir(baseDirName BaseDirEnum, path string) error {
-	_, ok := baseDirs[baseDirName]
-	if !ok {
+	return defaultLocations.SetBaseDir(baseDirName, path)
+}
+
+func Get(location LocationEnum) string {
+	return defaultLocations.Get(location)
+}
+
+func GetBaseDir(baseDir BaseDirEnum) string {
+	return defaultLocations.GetBaseDir(baseDir)
+}
+
+func GetTimestamped(key LocationEnum) string {
+	return defaultLocations.GetTimestamped(key)
+}
+
+func (l *Locations) SetBaseDir(baseDirName BaseDirEnum, path string) error {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	if _, ok := l.baseDirs[baseDirName]; !ok {
 		return fmt.Errorf("unknown base dir: %s", baseDirName)
 	}
+	baseDirs := make(map[BaseDirEnum]string, len(l.baseDirs))
+	for name, dir := range l.baseDirs {
+		baseDirs[name] = dir
+	}
 	baseDirs[baseDirName] = filepath.Clean(path)
-	return expandLocations()
+	locations, err := expandLocations(baseDirs)
+	if err != nil {
+		return err
+	}
+	l.baseDirs = baseDirs
+	l.locations = locations
+	return nil
 }
 
-func Get(location LocationEnum) string {
-	return locations[location]
+func (l *Locations) Get(location LocationEnum) string {
+	l.mut.RLock()
+	defer l.mut.RUnlock()
+	return l.locations[location]
 }
 
-func GetBaseDir(baseDir BaseDirEnum) string {
-	return baseDirs[baseDir]
+func (l *Locations) GetBaseDir(baseDir BaseDirEnum) string {
+	l.mut.RLock()
+	defer l.mut.RUnlock()
+	return l.baseDirs[baseDir]
 }
 
-// Platform dependent directories
-var baseDirs = map[BaseDirEnum]string{
-	ConfigBaseDir: defaultConfigDir(), // Overridden by -home flag
-	HomeBaseDir:   homeDir(),          // User's home directory, *not* -home flag
+func (l *Locations) GetTimestamped(key LocationEnum) string {
+	// We take the roundtrip via "${timestamp}" instead of passing the path
+	// directly through time.Format() to avoid issues when the path we are
+	// expanding contains numbers; otherwise for example
+	// /home/user2006/.../panic-20060102-150405.log would get both instances of
+	// 2006 replaced by 2015...
+	tpl := l.Get(key)
+	now := time.Now().Format("20060102-150405")
+	return strings.Replace(tpl, "${timestamp}", now, -1)
 }
 
 // Use the variables from baseDirs here
@@ -90,11 +159,9 @@ var locationTemplates = map[LocationEnum]string{
 	DefFolder:     "${home}/Sync",
 }
 
-var locations = make(map[LocationEnum]string)
-
-// expandLocations replaces the variables in the locations map with actual
-// directory locations.
-func expandLocations() error {
+// expandLocations returns the locations map with the variables replaced by
+// the given base directories.
+func expandLocations(baseDirs map[BaseDirEnum]string) (map[LocationEnum]string, error) {
 	newLocations := make(map[LocationEnum]string)
 	for key, dir := range locationTemplates {
 		for varName, value := range baseDirs {
@@ -103,12 +170,11 @@ func expandLocations() error {
 		var err error
 		dir, err = fs.ExpandTilde(dir)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		newLocations[key] = filepath.Clean(dir)
 	}
-	locations = newLocations
-	return nil
+	return newLocations, nil
 }
 
 // defaultConfigDir returns the default configuration directory, as figured
@@ -152,14 +218,3 @@ func homeDir() string {
 	}
 	return home
 }
-
-func GetTimestamped(key LocationEnum) string {
-	// We take the roundtrip via "${timestamp}" instead of passing the path
-	// directly through time.Format() to avoid issues when the path we are
-	// expanding contains numbers; otherwise for example
-	// /home/user2006/.../panic-20060102-150405.log would get both instances of
-	// 2006 replaced by 2015...
-	tpl := locations[key]
-	now := time.Now().Format("20060102-150405")
-	return strings.Replace(tpl, "${timestamp}", now, -1)
-}
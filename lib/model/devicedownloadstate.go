@@ -150,6 +150,23 @@ func (t *deviceDownloadState) GetBlockCounts(folder string) map[string]int {
 	return nil
 }
 
+// GetAllBlockCounts returns a map folder -> filename -> number of blocks
+// downloaded.
+func (t *deviceDownloadState) GetAllBlockCounts() map[string]map[string]int {
+	if t == nil {
+		return nil
+	}
+
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	res := make(map[string]map[string]int, len(t.folders))
+	for name, state := range t.folders {
+		res[name] = state.GetBlockCounts()
+	}
+	return res
+}
+
 func newDeviceDownloadState() *deviceDownloadState {
 	return &deviceDownloadState{
 		mut:     sync.NewRWMutex(),
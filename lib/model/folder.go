@@ -22,7 +22,6 @@ import (
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/ignore"
-	"github.com/syncthing/syncthing/lib/locations"
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/scanner"
@@ -33,9 +32,6 @@ import (
 	"github.com/thejerf/suture"
 )
 
-// scanLimiter limits the number of concurrent scans. A limit of zero means no limit.
-var scanLimiter = newByteSemaphore(0)
-
 type folder struct {
 	suture.Service
 	stateTracker
@@ -90,7 +86,7 @@ func newFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg conf
 		ignores: ignores,
 
 		scanInterval:        time.Duration(cfg.RescanIntervalS) * time.Second,
-		scanTimer:           time.NewTimer(time.Millisecond), // The first scan should be done immediately.
+		scanTimer:           time.NewTimer(initialScanDelay(cfg)),
 		scanNow:             make(chan rescanRequest),
 		scanDelay:           make(chan time.Duration),
 		initialScanFinished: make(chan struct{}),
@@ -104,6 +100,16 @@ func newFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg conf
 	}
 }
 
+// initialScanDelay returns when the first scan of the given folder is due.
+// Normally it should be done immediately, but it may be deferred to the
+// rescan interval to reduce the load while starting up.
+func initialScanDelay(cfg config.FolderConfiguration) time.Duration {
+	if cfg.DeferInitialScan && cfg.RescanIntervalS > 0 {
+		return time.Duration(cfg.RescanIntervalS) * time.Second
+	}
+	return time.Millisecond
+}
+
 func (f *folder) serve(ctx context.Context) {
 	atomic.AddInt32(&f.model.foldersRunning, 1)
 	defer atomic.AddInt32(&f.model.foldersRunning, -1)
@@ -224,7 +230,15 @@ func (f *folder) Jobs(_, _ int) ([]string, []string, int) {
 }
 
 func (f *folder) Scan(subdirs []string) error {
-	<-f.initialScanFinished
+	select {
+	case <-f.initialScanFinished:
+	default:
+		if f.DeferInitialScan {
+			// Don't wait for the deferred initial scan, do it right away.
+			f.Delay(0)
+		}
+		<-f.initialScanFinished
+	}
 	req := rescanRequest{
 		subdirs: subdirs,
 		err:     make(chan error),
@@ -269,7 +283,7 @@ func (f *folder) getHealthError() error {
 		return err
 	}
 
-	dbPath := locations.Get(locations.Database)
+	dbPath := f.model.dbPath
 	if usage, err := fs.NewFilesystem(fs.FilesystemTypeBasic, dbPath).Usage("."); err == nil {
 		if err = config.CheckFreeSpace(f.model.cfg.Options().MinHomeDiskFree, usage); err != nil {
 			return errors.Wrapf(err, "insufficient space on disk for database (%v)", dbPath)
@@ -312,8 +326,8 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 	f.setError(nil)
 	f.setState(FolderScanWaiting)
 
-	scanLimiter.take(1)
-	defer scanLimiter.give(1)
+	f.model.scanLimiter.take(1)
+	defer f.model.scanLimiter.give(1)
 
 	for i := range subDirs {
 		sub := osutil.NativeFilename(subDirs[i])
@@ -338,22 +352,35 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 
 	f.setState(FolderScanning)
 
+	tempLifetime := time.Duration(f.model.cfg.Options().KeepTemporariesH) * time.Hour
+	if f.KeepTemporariesH > 0 {
+		tempLifetime = time.Duration(f.KeepTemporariesH) * time.Hour
+	}
+
+	// The progress interval doesn't require a restart of the folder, so pick
+	// up the current value.
+	progressInterval := f.ScanProgressIntervalS
+	if cfg, ok := f.model.cfg.Folder(f.ID); ok {
+		progressInterval = cfg.ScanProgressIntervalS
+	}
+
 	mtimefs := f.fset.MtimeFS()
 	fchan := scanner.Walk(f.ctx, scanner.Config{
 		Folder:                f.ID,
 		Subs:                  subDirs,
 		Matcher:               f.ignores,
-		TempLifetime:          time.Duration(f.model.cfg.Options().KeepTemporariesH) * time.Hour,
+		TempLifetime:          tempLifetime,
 		CurrentFiler:          cFiler{f.fset},
 		Filesystem:            mtimefs,
 		IgnorePerms:           f.IgnorePerms,
 		AutoNormalize:         f.AutoNormalize,
 		Hashers:               f.model.numHashers(f.ID),
 		ShortID:               f.shortID,
-		ProgressTickIntervalS: f.ScanProgressIntervalS,
+		ProgressTickIntervalS: progressInterval,
 		LocalFlags:            f.localFlags,
 		ModTimeWindow:         f.ModTimeWindow(),
 		EventLogger:           f.evLogger,
+		IgnoreHidden:          f.IgnoreHidden,
 	})
 
 	batchFn := func(fs []protocol.FileInfo) error {
@@ -458,7 +485,7 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 				ignoredParent = ""
 			}
 
-			switch ignored := f.ignores.Match(file.Name).IsIgnored(); {
+			switch ignored := f.ignores.Match(file.Name).IsIgnored() || f.IgnoreHidden && fs.IsHidden(mtimefs, file.Name); {
 			case !file.IsIgnored() && ignored:
 				// File was not ignored at last pass but has been ignored.
 				if file.IsDirectory() {
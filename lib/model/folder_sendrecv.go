@@ -120,10 +120,6 @@ func newSendReceiveFolder(model *model, fset *db.FileSet, ignores *ignore.Matche
 	f.folder.puller = f
 	f.folder.Service = util.AsService(f.serve, f.String())
 
-	if f.Copiers == 0 {
-		f.Copiers = defaultCopiers
-	}
-
 	// If the configured max amount of pending data is zero, we use the
 	// default. If it's configured to something non-zero but less than the
 	// protocol block size we adjust it upwards accordingly.
@@ -244,7 +240,9 @@ func (f *sendReceiveFolder) pullerIteration(scanChan chan<- string) int {
 	doneWg := sync.NewWaitGroup()
 	updateWg := sync.NewWaitGroup()
 
-	l.Debugln(f, "copiers:", f.Copiers, "pullerPendingKiB:", f.PullerMaxPendingKiB)
+	copiers := f.copiers()
+
+	l.Debugln(f, "copiers:", copiers, "pullerPendingKiB:", f.pullerPendingKiB())
 
 	updateWg.Add(1)
 	go func() {
@@ -253,7 +251,7 @@ func (f *sendReceiveFolder) pullerIteration(scanChan chan<- string) int {
 		updateWg.Done()
 	}()
 
-	for i := 0; i < f.Copiers; i++ {
+	for i := 0; i < copiers; i++ {
 		copyWg.Add(1)
 		go func() {
 			// copierRoutine finishes when copyChan is closed
@@ -334,7 +332,7 @@ func (f *sendReceiveFolder) processNeeded(dbUpdateChan chan<- dbUpdateJob, copyC
 		file := intf.(protocol.FileInfo)
 
 		switch {
-		case f.ignores.ShouldIgnore(file.Name):
+		case f.ignores.ShouldIgnore(file.Name), f.IgnoreHidden && fs.IsHidden(f.fs, file.Name):
 			file.SetIgnored(f.shortID)
 			l.Debugln(f, "Handling ignored file", file)
 			dbUpdateChan <- dbUpdateJob{file, dbUpdateInvalidate}
@@ -1297,7 +1295,7 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 					return true
 				}
 
-				_, err = dstFd.WriteAt(buf, block.Offset)
+				err = f.copyBlock(dstFd, file, offset, buf, block)
 				if err != nil {
 					state.fail(errors.Wrap(err, "dst write"))
 
@@ -1322,8 +1320,10 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 						return false
 					}
 
-					_, err = fd.ReadAt(buf, int64(state.file.BlockSize())*int64(index))
-					fd.Close()
+					defer fd.Close()
+
+					srcOffset := int64(state.file.BlockSize()) * int64(index)
+					_, err = fd.ReadAt(buf, srcOffset)
 					if err != nil {
 						return false
 					}
@@ -1333,7 +1333,7 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 						return false
 					}
 
-					_, err = dstFd.WriteAt(buf, block.Offset)
+					err = f.copyBlock(dstFd, fd, srcOffset, buf, block)
 					if err != nil {
 						state.fail(errors.Wrap(err, "dst write"))
 					}
@@ -1387,8 +1387,34 @@ func verifyBuffer(buf []byte, block protocol.BlockInfo) error {
 	return nil
 }
 
+// copiers returns the number of copier routines to use, which is one when
+// running on battery and otherwise the configured number, falling back to the
+// global default and then defaultCopiers.
+func (f *sendReceiveFolder) copiers() int {
+	switch {
+	case f.model.onBattery():
+		return 1
+	case f.Copiers > 0:
+		return f.Copiers
+	}
+	if copiers := f.model.cfg.Options().DefaultCopiers; copiers > 0 {
+		return copiers
+	}
+	return defaultCopiers
+}
+
+// pullerPendingKiB returns the maximum amount of data requested but not yet
+// received, which is reduced to the minimum when running on battery.
+func (f *sendReceiveFolder) pullerPendingKiB() int {
+	if f.model.onBattery() {
+		return protocol.MaxBlockSize / 1024
+	}
+	return f.PullerMaxPendingKiB
+}
+
 func (f *sendReceiveFolder) pullerRoutine(in <-chan pullBlockState, out chan<- *sharedPullerState) {
-	requestLimiter := newByteSemaphore(f.PullerMaxPendingKiB * 1024)
+	requestLimiter := newByteSemaphore(f.pullerPendingKiB() * 1024)
+	pullerLimiter := newByteSemaphore(f.model.cfg.Options().DefaultPullers)
 	wg := sync.NewWaitGroup()
 
 	for state := range in {
@@ -1401,17 +1427,23 @@ func (f *sendReceiveFolder) pullerRoutine(in <-chan pullBlockState, out chan<- *
 
 		// The requestLimiter limits how many pending block requests we have
 		// ongoing at any given time, based on the size of the blocks
-		// themselves.
+		// themselves. The pullerLimiter additionally limits their number
+		// if configured, and the model's pullRequestLimiter their number
+		// across all folders.
 
 		state := state
 		bytes := int(state.block.Size)
 
 		requestLimiter.take(bytes)
+		pullerLimiter.take(1)
+		f.model.pullRequestLimiter.take(1)
 		wg.Add(1)
 
 		go func() {
 			defer wg.Done()
 			defer requestLimiter.give(bytes)
+			defer pullerLimiter.give(1)
+			defer f.model.pullRequestLimiter.give(1)
 
 			f.pullBlock(state, out)
 		}()
@@ -1419,6 +1451,24 @@ func (f *sendReceiveFolder) pullerRoutine(in <-chan pullBlockState, out chan<- *
 	wg.Wait()
 }
 
+// copyBlock writes the verified block contents in buf, found at srcOffset in
+// src, to the temporary file. Unless the standard copy range method is
+// configured the data is copied from src using that method instead, which
+// may allow the filesystem to share rather than duplicate it. Should that
+// fail, e.g. because the filesystem doesn't support it, the buffer is
+// written as usual.
+func (f *sendReceiveFolder) copyBlock(dstFd *lockedWriterAt, src fs.File, srcOffset int64, buf []byte, block protocol.BlockInfo) error {
+	if f.CopyRangeMethod != fs.CopyRangeMethodStandard {
+		err := dstFd.CopyRange(f.CopyRangeMethod, src, srcOffset, block.Offset, int64(block.Size))
+		if err == nil {
+			return nil
+		}
+		l.Debugf("%v copy range %v, falling back to writing the block: %v", f, f.CopyRangeMethod, err)
+	}
+	_, err := dstFd.WriteAt(buf, block.Offset)
+	return err
+}
+
 func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPullerState) {
 	// Get an fd to the temporary file. Technically we don't need it until
 	// after fetching the block, but if we run into an error here there is
@@ -1697,11 +1747,17 @@ func (f *sendReceiveFolder) pullScannerRoutine(scanChan <-chan string) {
 }
 
 func (f *sendReceiveFolder) inConflict(current, replacement protocol.Vector) bool {
+	return inConflict(current, replacement, f.shortID)
+}
+
+// inConflict returns whether the replacement of a local item with the given
+// version, as seen by the device with the given short ID, conflicts with it.
+func inConflict(current, replacement protocol.Vector, shortID protocol.ShortID) bool {
 	if current.Concurrent(replacement) {
 		// Obvious case
 		return true
 	}
-	if replacement.Counter(f.shortID) > current.Counter(f.shortID) {
+	if replacement.Counter(shortID) > current.Counter(shortID) {
 		// The replacement file contains a higher version for ourselves than
 		// what we have. This isn't supposed to be possible, since it's only
 		// we who can increment that counter. We take it as a sign that
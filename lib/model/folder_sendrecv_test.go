@@ -1036,3 +1036,88 @@ func startCopier(f *sendReceiveFolder, pullChan chan<- pullBlockState, finisherC
 	}()
 	return copyChan, wg
 }
+
+func TestBatteryMode(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+
+	f.Hashers = 4
+	f.Copiers = 3
+	f.PullerMaxPendingKiB = 4096
+	m.fmut.Lock()
+	m.folderCfgs[f.ID] = f.FolderConfiguration
+	m.fmut.Unlock()
+	opts := m.cfg.Options()
+	opts.MaxConcurrentScans = 2
+	m.cfg.SetOptions(opts)
+
+	// Switching back and forth must restore the configured values.
+	cases := []struct {
+		onBattery  bool
+		hashers    int
+		copiers    int
+		pendingKiB int
+		scans      int
+	}{
+		{false, 4, 3, 4096, 2},
+		{true, 1, 1, protocol.MaxBlockSize / 1024, 1},
+		{true, 1, 1, protocol.MaxBlockSize / 1024, 1},
+		{false, 4, 3, 4096, 2},
+	}
+	for i, tc := range cases {
+		m.SetBatteryMode(tc.onBattery)
+		if hashers := m.numHashers(f.ID); hashers != tc.hashers {
+			t.Errorf("%d: expected %d hashers, got %d", i, tc.hashers, hashers)
+		}
+		if copiers := f.copiers(); copiers != tc.copiers {
+			t.Errorf("%d: expected %d copiers, got %d", i, tc.copiers, copiers)
+		}
+		if pendingKiB := f.pullerPendingKiB(); pendingKiB != tc.pendingKiB {
+			t.Errorf("%d: expected %d pending KiB, got %d", i, tc.pendingKiB, pendingKiB)
+		}
+		m.scanLimiter.mut.Lock()
+		scans := m.scanLimiter.max
+		m.scanLimiter.mut.Unlock()
+		if scans != tc.scans {
+			t.Errorf("%d: expected %d concurrent scans, got %d", i, tc.scans, scans)
+		}
+	}
+}
+
+func TestDefaultWorkerCounts(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+
+	autoHashers := 1
+	if runtime.GOOS != "windows" && runtime.GOOS != "darwin" && runtime.GOMAXPROCS(-1) > 1 {
+		autoHashers = runtime.GOMAXPROCS(-1)
+	}
+
+	cases := []struct {
+		folderCopiers, globalCopiers, copiers int
+		folderHashers, globalHashers, hashers int
+	}{
+		{0, 0, defaultCopiers, 0, 0, autoHashers},
+		{0, 4, 4, 0, 2, 2},
+		{3, 0, 3, 5, 0, 5},
+		{3, 4, 3, 5, 2, 5},
+	}
+	for i, tc := range cases {
+		f.Copiers = tc.folderCopiers
+		f.Hashers = tc.folderHashers
+		m.fmut.Lock()
+		m.folderCfgs[f.ID] = f.FolderConfiguration
+		m.fmut.Unlock()
+		opts := m.cfg.Options()
+		opts.DefaultCopiers = tc.globalCopiers
+		opts.DefaultHashers = tc.globalHashers
+		m.cfg.SetOptions(opts)
+
+		if copiers := f.copiers(); copiers != tc.copiers {
+			t.Errorf("%d: expected %d copiers, got %d", i, tc.copiers, copiers)
+		}
+		if hashers := m.numHashers(f.ID); hashers != tc.hashers {
+			t.Errorf("%d: expected %d hashers, got %d", i, tc.hashers, hashers)
+		}
+	}
+}
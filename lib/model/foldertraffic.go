@@ -0,0 +1,66 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// FolderTraffic is the number of bytes transferred for a folder since the
+// model was started. Data is the contents of files requested by or from
+// other devices, index the metadata exchanged in index messages. Both are
+// counted before compression and without protocol overhead.
+type FolderTraffic struct {
+	DataBytesSent      int64 `json:"dataBytesSent"`
+	DataBytesReceived  int64 `json:"dataBytesReceived"`
+	IndexBytesSent     int64 `json:"indexBytesSent"`
+	IndexBytesReceived int64 `json:"indexBytesReceived"`
+}
+
+// folderTraffic accumulates the FolderTraffic of all folders. It is safe for
+// use from multiple goroutines.
+type folderTraffic struct {
+	traffic map[string]FolderTraffic
+	mut     sync.Mutex
+}
+
+func newFolderTraffic() *folderTraffic {
+	return &folderTraffic{
+		traffic: make(map[string]FolderTraffic),
+		mut:     sync.NewMutex(),
+	}
+}
+
+func (t *folderTraffic) get(folder string) FolderTraffic {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.traffic[folder]
+}
+
+func (t *folderTraffic) add(folder string, update func(traffic *FolderTraffic)) {
+	t.mut.Lock()
+	traffic := t.traffic[folder]
+	update(&traffic)
+	t.traffic[folder] = traffic
+	t.mut.Unlock()
+}
+
+func (t *folderTraffic) dataSent(folder string, bytes int) {
+	t.add(folder, func(traffic *FolderTraffic) { traffic.DataBytesSent += int64(bytes) })
+}
+
+func (t *folderTraffic) dataReceived(folder string, bytes int) {
+	t.add(folder, func(traffic *FolderTraffic) { traffic.DataBytesReceived += int64(bytes) })
+}
+
+func (t *folderTraffic) indexSent(folder string, bytes int) {
+	t.add(folder, func(traffic *FolderTraffic) { traffic.IndexBytesSent += int64(bytes) })
+}
+
+func (t *folderTraffic) indexReceived(folder string, bytes int) {
+	t.add(folder, func(traffic *FolderTraffic) { traffic.IndexBytesReceived += int64(bytes) })
+}
@@ -0,0 +1,37 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import "testing"
+
+func TestFolderTraffic(t *testing.T) {
+	traffic := newFolderTraffic()
+
+	if res := traffic.get("default"); res != (FolderTraffic{}) {
+		t.Errorf("unexpected traffic for new folder: %+v", res)
+	}
+
+	traffic.dataSent("default", 100)
+	traffic.dataSent("default", 28)
+	traffic.dataReceived("default", 5)
+	traffic.indexSent("default", 7)
+	traffic.indexReceived("default", 3)
+	traffic.dataReceived("other", 1000)
+
+	expected := FolderTraffic{
+		DataBytesSent:      128,
+		DataBytesReceived:  5,
+		IndexBytesSent:     7,
+		IndexBytesReceived: 3,
+	}
+	if res := traffic.get("default"); res != expected {
+		t.Errorf("traffic %+v != expected %+v", res, expected)
+	}
+	if res := traffic.get("other"); res != (FolderTraffic{DataBytesReceived: 1000}) {
+		t.Errorf("unexpected traffic for other folder: %+v", res)
+	}
+}
@@ -11,13 +11,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net"
 	"path/filepath"
 	"reflect"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	stdsync "sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -68,12 +71,30 @@ type Availability struct {
 	FromTemporary bool              `json:"fromTemporary"`
 }
 
+const (
+	TransferDownload = "download"
+	TransferUpload   = "upload"
+)
+
+// A Transfer describes a file currently being downloaded or uploaded. The
+// device is only set for uploads.
+type Transfer struct {
+	Folder     string `json:"folder"`
+	Name       string `json:"name"`
+	Direction  string `json:"direction"`
+	Device     string `json:"device,omitempty"`
+	BytesDone  int64  `json:"bytesDone"`
+	BytesTotal int64  `json:"bytesTotal"`
+}
+
 type Model interface {
 	suture.Service
 
 	connections.Model
 
 	ResetFolder(folder string)
+	CleanTemporaries(folder string) (int, error)
+	SetBatteryMode(onBattery bool)
 	DelayScan(folder string, next time.Duration)
 	ScanFolder(folder string) error
 	ScanFolders() map[string]error
@@ -86,6 +107,8 @@ type Model interface {
 	BringToFront(folder, file string)
 	GetIgnores(folder string) ([]string, []string, error)
 	SetIgnores(folder string, content []string) error
+	PreviewIgnores(folder string, content []string) ([]string, error)
+	PreviewPull(folder string) (PullPreview, error)
 
 	GetFolderVersions(folder string) (map[string][]versioner.FileVersion, error)
 	RestoreFolderVersions(folder string, versions map[string]time.Time) (map[string]string, error)
@@ -106,7 +129,10 @@ type Model interface {
 	RemoteSequence(folder string) (int64, bool)
 
 	Completion(device protocol.DeviceID, folder string) FolderCompletion
+	ActiveTransfers(limit int) ([]Transfer, int)
+	FolderActiveTransfers(folder string, limit int) ([]Transfer, int)
 	ConnectionStats() map[string]interface{}
+	FolderTraffic(folder string) FolderTraffic
 	DeviceStatistics() (map[string]stats.DeviceStatistics, error)
 	FolderStatistics() (map[string]stats.FolderStatistics, error)
 	UsageReportingStats(version int, preview bool) map[string]interface{}
@@ -120,6 +146,7 @@ type model struct {
 
 	cfg               config.Wrapper
 	db                *db.Lowlevel
+	dbPath            string
 	finder            *db.BlockFinder
 	progressEmitter   *ProgressEmitter
 	id                protocol.DeviceID
@@ -149,7 +176,12 @@ type model struct {
 	deviceDownloads     map[protocol.DeviceID]*deviceDownloadState
 	remotePausedFolders map[protocol.DeviceID][]string // deviceID -> folders
 
+	traffic            *folderTraffic
+	pullRequestLimiter *byteSemaphore // limits the block requests in flight across all folders
+	scanLimiter        *byteSemaphore // limits the concurrent scans across all folders, zero means no limit
+
 	foldersRunning int32 // for testing only
+	batteryMode    int32 // accessed atomically, 1 when running on battery
 }
 
 type folderFactory func(*model, *db.FileSet, *ignore.Matcher, config.FolderConfiguration, versioner.Versioner, fs.Filesystem, events.Logger) service
@@ -176,7 +208,7 @@ var (
 // NewModel creates and starts a new model. The model starts in read-only mode,
 // where it sends index information to connected peers and responds to requests
 // for file data without altering the local folder in any way.
-func NewModel(cfg config.Wrapper, id protocol.DeviceID, clientName, clientVersion string, ldb *db.Lowlevel, protectedFiles []string, evLogger events.Logger) Model {
+func NewModel(cfg config.Wrapper, id protocol.DeviceID, clientName, clientVersion string, ldb *db.Lowlevel, dbPath string, protectedFiles []string, evLogger events.Logger) Model {
 	m := &model{
 		Supervisor: suture.New("model", suture.Spec{
 			Log: func(line string) {
@@ -186,6 +218,7 @@ func NewModel(cfg config.Wrapper, id protocol.DeviceID, clientName, clientVersio
 		}),
 		cfg:                 cfg,
 		db:                  ldb,
+		dbPath:              dbPath,
 		finder:              db.NewBlockFinder(ldb),
 		progressEmitter:     NewProgressEmitter(cfg, evLogger),
 		id:                  id,
@@ -210,12 +243,15 @@ func NewModel(cfg config.Wrapper, id protocol.DeviceID, clientName, clientVersio
 		remotePausedFolders: make(map[protocol.DeviceID][]string),
 		fmut:                sync.NewRWMutex(),
 		pmut:                sync.NewRWMutex(),
+		traffic:             newFolderTraffic(),
+		pullRequestLimiter:  newByteSemaphore(maxOutstandingRequests(cfg.Options())),
+		scanLimiter:         newByteSemaphore(0),
 	}
 	for devID := range cfg.Devices() {
 		m.deviceStatRefs[devID] = stats.NewDeviceStatisticsReference(m.db, devID.String())
 	}
 	m.Add(m.progressEmitter)
-	scanLimiter.setCapacity(cfg.Options().MaxConcurrentScans)
+	m.scanLimiter.setCapacity(m.maxConcurrentScans(cfg.Options()))
 
 	return m
 }
@@ -705,6 +741,110 @@ func (m *model) ConnectionStats() map[string]interface{} {
 	return res
 }
 
+// ActiveTransfers returns the files currently being downloaded by us or
+// uploaded to the connected devices, sorted by folder, name and direction.
+// At most limit transfers are returned unless limit is zero or negative. The
+// total number of transfers is returned as well. Downloads and uploads are
+// taken at the same time, while holding the locks which guard the folders and
+// connections; the uploaded files are looked up in the database afterwards.
+func (m *model) ActiveTransfers(limit int) ([]Transfer, int) {
+	return m.activeTransfers("", limit)
+}
+
+// FolderActiveTransfers is like ActiveTransfers but only returns the
+// transfers of the given folder.
+func (m *model) FolderActiveTransfers(folder string, limit int) ([]Transfer, int) {
+	if folder == "" {
+		return nil, 0
+	}
+	return m.activeTransfers(folder, limit)
+}
+
+// activeTransfers returns the transfers of the given folder, or of all
+// folders if it is empty.
+func (m *model) activeTransfers(folder string, limit int) ([]Transfer, int) {
+	type upload struct {
+		device protocol.DeviceID
+		folder string
+		name   string
+		blocks int
+		fs     *db.FileSet
+	}
+	var transfers []Transfer
+	var uploads []upload
+
+	m.fmut.RLock()
+	m.pmut.RLock()
+	for id, pullers := range m.progressEmitter.pullerProgresses() {
+		if _, ok := m.folderFiles[id]; !ok || (folder != "" && id != folder) {
+			continue
+		}
+		for name, progress := range pullers {
+			transfers = append(transfers, Transfer{
+				Folder:     id,
+				Name:       name,
+				Direction:  TransferDownload,
+				BytesDone:  progress.BytesDone,
+				BytesTotal: progress.BytesTotal,
+			})
+		}
+	}
+	for device, downloads := range m.deviceDownloads {
+		for id, counts := range downloads.GetAllBlockCounts() {
+			fs, ok := m.folderFiles[id]
+			if !ok || (folder != "" && id != folder) {
+				continue
+			}
+			for name, blocks := range counts {
+				uploads = append(uploads, upload{device, id, name, blocks, fs})
+			}
+		}
+	}
+	m.pmut.RUnlock()
+	m.fmut.RUnlock()
+
+	// Looking up the files may take a while with many transfers, so it
+	// happens without holding the locks.
+	for _, up := range uploads {
+		file, ok := up.fs.GetGlobalTruncated(up.name)
+		if !ok {
+			continue
+		}
+		done := blocksToSize(file.BlockSize(), up.blocks)
+		if done > file.Size {
+			done = file.Size
+		}
+		transfers = append(transfers, Transfer{
+			Folder:     up.folder,
+			Name:       up.name,
+			Direction:  TransferUpload,
+			Device:     up.device.String(),
+			BytesDone:  done,
+			BytesTotal: file.Size,
+		})
+	}
+
+	sort.Slice(transfers, func(a, b int) bool {
+		ta, tb := transfers[a], transfers[b]
+		if ta.Folder != tb.Folder {
+			return ta.Folder < tb.Folder
+		}
+		if ta.Name != tb.Name {
+			return ta.Name < tb.Name
+		}
+		if ta.Direction != tb.Direction {
+			return ta.Direction < tb.Direction
+		}
+		return ta.Device < tb.Device
+	})
+
+	total := len(transfers)
+	if limit > 0 && total > limit {
+		transfers = transfers[:limit]
+	}
+	return transfers, total
+}
+
 // DeviceStatistics returns statistics about each device
 func (m *model) DeviceStatistics() (map[string]stats.DeviceStatistics, error) {
 	m.fmut.RLock()
@@ -1091,11 +1231,14 @@ func (m *model) handleIndex(deviceID protocol.DeviceID, folder string, fs []prot
 	if !update {
 		files.Drop(deviceID)
 	}
+	size := 0
 	for i := range fs {
 		// The local flags should never be transmitted over the wire. Make
 		// sure they look like they weren't.
 		fs[i].LocalFlags = 0
+		size += fs[i].ProtoSize()
 	}
+	m.traffic.indexReceived(folder, size)
 	files.Update(deviceID, fs)
 
 	m.evLogger.Log(events.RemoteIndexUpdated, map[string]interface{}{
@@ -1248,6 +1391,7 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 			fset:         fs,
 			prevSequence: startSequence,
 			evLogger:     m.evLogger,
+			traffic:      m.traffic,
 		}
 		is.Service = util.AsService(is.serve, is.String())
 		// The token isn't tracked as the service stops when the connection
@@ -1658,6 +1802,12 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 		}()
 	}
 
+	defer func() {
+		if err == nil && deviceID != protocol.LocalDeviceID {
+			m.traffic.dataSent(folder, int(size))
+		}
+	}()
+
 	// Only check temp files if the flag is set, and if we are set to advertise
 	// the temp indexes.
 	if fromTemporary && !folderCfg.DisableTempIndexes {
@@ -1776,6 +1926,13 @@ func (m *model) Connection(deviceID protocol.DeviceID) (connections.Connection,
 	return cn, ok
 }
 
+// NumConnections returns the number of devices currently connected.
+func (m *model) NumConnections() int {
+	m.pmut.RLock()
+	defer m.pmut.RUnlock()
+	return len(m.conn)
+}
+
 func (m *model) GetIgnores(folder string) ([]string, []string, error) {
 	m.fmut.RLock()
 	cfg, cfgOk := m.folderCfgs[folder]
@@ -1836,6 +1993,38 @@ func (m *model) SetIgnores(folder string, content []string) error {
 	return nil
 }
 
+// PreviewIgnores returns the names of the files and directories in the
+// local index which are not ignored currently but would be if the given
+// ignore patterns were set.
+func (m *model) PreviewIgnores(folder string, content []string) ([]string, error) {
+	m.fmut.RLock()
+	cfg, cfgOk := m.folderCfgs[folder]
+	files, filesOk := m.folderFiles[folder]
+	m.fmut.RUnlock()
+
+	if !cfgOk || !filesOk {
+		return nil, fmt.Errorf("folder %s does not exist or is paused", folder)
+	}
+
+	ignores := ignore.New(cfg.Filesystem())
+	if err := ignores.Parse(strings.NewReader(strings.Join(content, "\n")), ".stignore"); err != nil {
+		return nil, err
+	}
+
+	var ignored []string
+	files.WithHaveTruncated(protocol.LocalDeviceID, func(fi db.FileIntf) bool {
+		f := fi.(db.FileInfoTruncated)
+		if f.IsDeleted() || f.IsInvalid() {
+			return true
+		}
+		if ignores.ShouldIgnore(f.Name) {
+			ignored = append(ignored, f.Name)
+		}
+		return true
+	})
+	return ignored, nil
+}
+
 // OnHello is called when an device connects to us.
 // This allows us to extract some information from the Hello message
 // and add it to a list of known devices ahead of any checks.
@@ -1996,6 +2185,7 @@ type indexSender struct {
 	prevSequence int64
 	evLogger     events.Logger
 	connClosed   chan struct{}
+	traffic      *folderTraffic
 }
 
 func (s *indexSender) serve(ctx context.Context) {
@@ -2066,6 +2256,7 @@ func (s *indexSender) sendIndexTo(ctx context.Context) error {
 	batch := newFileInfoBatch(nil)
 	batch.flushFn = func(fs []protocol.FileInfo) error {
 		l.Debugf("%v: Sending %d files (<%d bytes)", s, len(batch.infos), batch.size)
+		s.traffic.indexSent(s.folder, batch.size)
 		if initial {
 			initial = false
 			return s.conn.Index(ctx, s.folder, fs)
@@ -2134,7 +2325,17 @@ func (m *model) requestGlobal(ctx context.Context, deviceID protocol.DeviceID, f
 
 	l.Debugf("%v REQ(out): %s: %q / %q o=%d s=%d h=%x wh=%x ft=%t", m, deviceID, folder, name, offset, size, hash, weakHash, fromTemporary)
 
-	return nc.Request(ctx, folder, name, offset, size, hash, weakHash, fromTemporary)
+	data, err := nc.Request(ctx, folder, name, offset, size, hash, weakHash, fromTemporary)
+	if err == nil {
+		m.traffic.dataReceived(folder, len(data))
+	}
+	return data, err
+}
+
+// FolderTraffic returns the number of bytes transferred for the given folder
+// since the model was started.
+func (m *model) FolderTraffic(folder string) FolderTraffic {
+	return m.traffic.get(folder)
 }
 
 func (m *model) ScanFolders() map[string]error {
@@ -2196,6 +2397,10 @@ func (m *model) DelayScan(folder string, next time.Duration) {
 // numHashers returns the number of hasher routines to use for a given folder,
 // taking into account configuration and available CPU cores.
 func (m *model) numHashers(folder string) int {
+	if m.onBattery() {
+		return 1
+	}
+
 	m.fmut.RLock()
 	folderCfg := m.folderCfgs[folder]
 	numFolders := len(m.folderCfgs)
@@ -2206,6 +2411,11 @@ func (m *model) numHashers(folder string) int {
 		return folderCfg.Hashers
 	}
 
+	if hashers := m.cfg.Options().DefaultHashers; hashers > 0 {
+		// Global default set in the config, use that.
+		return hashers
+	}
+
 	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
 		// Interactive operating systems; don't load the system too heavily by
 		// default.
@@ -2222,6 +2432,42 @@ func (m *model) numHashers(folder string) int {
 	return 1
 }
 
+// SetBatteryMode sets whether the device is running on battery. While it is,
+// at most one folder is scanned at a time using a single hasher, and files
+// are pulled with a single copier and the minimum amount of pending
+// requests, regardless of the configured values. The concurrency of scans
+// changes right away, the others take effect with the next scan or pull.
+func (m *model) SetBatteryMode(onBattery bool) {
+	var v int32
+	if onBattery {
+		v = 1
+	}
+	atomic.StoreInt32(&m.batteryMode, v)
+	m.scanLimiter.setCapacity(m.maxConcurrentScans(m.cfg.Options()))
+}
+
+func (m *model) onBattery() bool {
+	return atomic.LoadInt32(&m.batteryMode) != 0
+}
+
+// maxOutstandingRequests returns the number of block requests allowed to be
+// in flight across all folders, zero meaning no limit.
+func maxOutstandingRequests(opts config.OptionsConfiguration) int {
+	if opts.MaxOutstandingRequests < 0 {
+		return 0
+	}
+	return opts.MaxOutstandingRequests
+}
+
+// maxConcurrentScans returns the number of concurrent scans to allow, zero
+// meaning no limit.
+func (m *model) maxConcurrentScans(opts config.OptionsConfiguration) int {
+	if m.onBattery() {
+		return 1
+	}
+	return opts.MaxConcurrentScans
+}
+
 // generateClusterConfig returns a ClusterConfigMessage that is correct for
 // the given peer device
 func (m *model) generateClusterConfig(device protocol.DeviceID) protocol.ClusterConfig {
@@ -2552,6 +2798,54 @@ func (m *model) ResetFolder(folder string) {
 	db.DropFolder(m.db, folder)
 }
 
+// CleanTemporaries removes the temporary files left behind by interrupted
+// downloads in the given folder, except those of files currently being
+// pulled. It returns the number of removed files.
+func (m *model) CleanTemporaries(folder string) (int, error) {
+	m.fmut.RLock()
+	cfg, ok := m.folderCfgs[folder]
+	runner := m.folderRunners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return 0, errFolderMissing
+	}
+
+	// The progress emitter only knows about the files being pulled if it is
+	// enabled, the job queue of the folder always does.
+	inUse := make(map[string]struct{})
+	for name := range m.progressEmitter.pullerProgresses()[folder] {
+		inUse[fs.TempName(name)] = struct{}{}
+	}
+	if runner != nil {
+		progress, _, _ := runner.Jobs(1, math.MaxInt32)
+		for _, name := range progress {
+			inUse[fs.TempName(name)] = struct{}{}
+		}
+	}
+
+	ffs := cfg.Filesystem()
+	removed := 0
+	err := ffs.Walk(".", func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsRegular() || !fs.IsTemporary(path) {
+			return nil
+		}
+		if _, ok := inUse[path]; ok {
+			return nil
+		}
+		if err := ffs.Remove(path); err != nil {
+			l.Infof("Removing temporary file %s in folder %s: %v", path, cfg.Description(), err)
+			return nil
+		}
+		l.Debugln(m, "removed temporary file", path, "in folder", cfg.Description())
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
 func (m *model) String() string {
 	return fmt.Sprintf("model@%p", m)
 }
@@ -2650,7 +2944,8 @@ func (m *model) CommitConfiguration(from, to config.Configuration) bool {
 	}
 	m.fmut.Unlock()
 
-	scanLimiter.setCapacity(to.Options.MaxConcurrentScans)
+	m.scanLimiter.setCapacity(m.maxConcurrentScans(to.Options))
+	m.pullRequestLimiter.setCapacity(maxOutstandingRequests(to.Options))
 
 	// Some options don't require restart as those components handle it fine
 	// by themselves. Compare the options structs containing only the
@@ -16,6 +16,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"runtime/pprof"
 	"strconv"
@@ -34,6 +35,7 @@ import (
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	srand "github.com/syncthing/syncthing/lib/rand"
+	ssync "github.com/syncthing/syncthing/lib/sync"
 	"github.com/syncthing/syncthing/lib/testutils"
 	"github.com/syncthing/syncthing/lib/versioner"
 )
@@ -1514,6 +1516,38 @@ func TestIgnores(t *testing.T) {
 	changeIgnores(t, m, []string{})
 }
 
+func TestPreviewIgnores(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	m.fmut.RLock()
+	set := m.folderFiles["default"]
+	m.fmut.RUnlock()
+	version := protocol.Vector{Counters: []protocol.Counter{{ID: myID.Short(), Value: 1}}}
+	set.Update(protocol.LocalDeviceID, []protocol.FileInfo{
+		{Name: "keep", Version: version},
+		{Name: "foo.tmp", Version: version},
+		{Name: "deleted.tmp", Version: version, Deleted: true},
+		{Name: "ignored.tmp", Version: version, LocalFlags: protocol.FlagLocalIgnored},
+	})
+
+	ignored, err := m.PreviewIgnores("default", []string{"*.tmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ignored) != 1 || ignored[0] != "foo.tmp" {
+		t.Errorf("Expected only foo.tmp to become ignored, got %v", ignored)
+	}
+
+	if _, err := m.PreviewIgnores("default", []string{"#include nonexistent"}); err == nil {
+		t.Error("No error for invalid patterns")
+	}
+	if _, err := m.PreviewIgnores("doesnotexist", nil); err == nil {
+		t.Error("No error for nonexistent folder")
+	}
+}
+
 func TestEmptyIgnores(t *testing.T) {
 	testOs := &fatalOs{t}
 
@@ -3254,6 +3288,49 @@ func TestFolderRestartZombies(t *testing.T) {
 	}
 }
 
+// TestOptionChangeRestartsNoFolders checks that changing an option which is
+// unrelated to the folders doesn't restart any of them.
+func TestOptionChangeRestartsNoFolders(t *testing.T) {
+	wrapper := createTmpWrapper(defaultCfg.Copy())
+	folderCfg, _ := wrapper.Folder("default")
+	folderCfg.FilesystemType = fs.FilesystemTypeFake
+	wrapper.SetFolder(folderCfg)
+	otherCfg := folderCfg.Copy()
+	otherCfg.ID = "other"
+	otherCfg.Path = "other"
+	wrapper.SetFolder(otherCfg)
+
+	m := setupModel(wrapper)
+	defer cleanupModel(m)
+
+	m.fmut.RLock()
+	before := make(map[string]service, len(m.folderRunners))
+	for id, runner := range m.folderRunners {
+		before[id] = runner
+	}
+	m.fmut.RUnlock()
+	if len(before) != 2 {
+		t.Fatal("Expected two folder runners, not", len(before))
+	}
+
+	opts := wrapper.Options()
+	opts.ReconnectIntervalS++
+	opts.MaxConcurrentScans++
+	w, err := wrapper.SetOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Wait()
+
+	m.fmut.RLock()
+	defer m.fmut.RUnlock()
+	for id, runner := range before {
+		if m.folderRunners[id] != runner {
+			t.Errorf("Folder %v has been restarted", id)
+		}
+	}
+}
+
 func TestRequestLimit(t *testing.T) {
 	wrapper := createTmpWrapper(defaultCfg.Copy())
 	dev, _ := wrapper.Device(device1)
@@ -3449,3 +3526,188 @@ func TestDeviceWasSeen(t *testing.T) {
 		t.Error("device should have been seen now")
 	}
 }
+
+func TestActiveTransfers(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	opts := w.Options()
+	opts.ProgressUpdateIntervalS = 60 // the progress emitter is disabled otherwise
+	w.SetOptions(opts)
+	m, _ := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	blocks := func(n int) []protocol.BlockInfo {
+		res := make([]protocol.BlockInfo, n)
+		for i := range res {
+			res[i] = protocol.BlockInfo{Offset: int64(i * protocol.MinBlockSize), Size: protocol.MinBlockSize}
+		}
+		return res
+	}
+	version := protocol.Vector{}.Update(myID.Short())
+
+	// A file uploaded to device1, which has got two of its three blocks.
+	up := protocol.FileInfo{Name: "up", Size: 3 * protocol.MinBlockSize, Blocks: blocks(3), Version: version}
+	m.fmut.RLock()
+	m.folderFiles["default"].Update(protocol.LocalDeviceID, []protocol.FileInfo{up})
+	m.fmut.RUnlock()
+	m.DownloadProgress(device1, "default", []protocol.FileDownloadProgressUpdate{
+		{UpdateType: protocol.UpdateTypeAppend, Name: "up", Version: version, BlockIndexes: []int32{0, 1}},
+	})
+
+	// Files downloaded by us, one of them in a folder which doesn't exist.
+	for _, puller := range []*sharedPullerState{
+		{folder: "default", file: protocol.FileInfo{Name: "down", Blocks: blocks(4)}, pullTotal: 4, pullNeeded: 1},
+		{folder: "default", file: protocol.FileInfo{Name: "a-down", Blocks: blocks(2)}, pullTotal: 2, pullNeeded: 2},
+		{folder: "missing", file: protocol.FileInfo{Name: "down", Blocks: blocks(1)}, pullTotal: 1, pullNeeded: 1},
+	} {
+		puller.mut = ssync.NewRWMutex()
+		m.progressEmitter.Register(puller)
+	}
+
+	// The sizes are estimated from the number of blocks like for the
+	// download progress events.
+	all := []Transfer{
+		{Folder: "default", Name: "a-down", Direction: TransferDownload, BytesDone: blocksToSize(protocol.MinBlockSize, 0), BytesTotal: blocksToSize(protocol.MinBlockSize, 2)},
+		{Folder: "default", Name: "down", Direction: TransferDownload, BytesDone: blocksToSize(protocol.MinBlockSize, 3), BytesTotal: blocksToSize(protocol.MinBlockSize, 4)},
+		{Folder: "default", Name: "up", Direction: TransferUpload, Device: device1.String(), BytesDone: blocksToSize(protocol.MinBlockSize, 2), BytesTotal: up.Size},
+	}
+	cases := []struct {
+		limit    int
+		expected []Transfer
+	}{
+		{0, all},
+		{-1, all},
+		{3, all},
+		{10, all},
+		{2, all[:2]},
+		{1, all[:1]},
+	}
+	for _, tc := range cases {
+		transfers, total := m.ActiveTransfers(tc.limit)
+		if total != len(all) {
+			t.Errorf("limit %d: expected a total of %d, got %d", tc.limit, len(all), total)
+		}
+		if !reflect.DeepEqual(transfers, tc.expected) {
+			t.Errorf("limit %d: expected %+v, got %+v", tc.limit, tc.expected, transfers)
+		}
+	}
+
+	if transfers, total := m.FolderActiveTransfers("default", 0); total != len(all) || !reflect.DeepEqual(transfers, all) {
+		t.Errorf("folder default: expected %+v, got %+v (total %d)", all, transfers, total)
+	}
+	for _, folder := range []string{"missing", ""} {
+		if transfers, total := m.FolderActiveTransfers(folder, 0); total != 0 || len(transfers) != 0 {
+			t.Errorf("folder %q: expected no transfers, got %+v (total %d)", folder, transfers, total)
+		}
+	}
+}
+
+func TestCleanTemporaries(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	// A file which is being pulled, as far as the folder is concerned.
+	queue := newJobQueue()
+	queue.Push("pulling", 0, time.Time{})
+	queue.Pop()
+	m.fmut.Lock()
+	m.folderRunners["default"] = &sendReceiveFolder{queue: queue}
+	m.fmut.Unlock()
+
+	ffs := fcfg.Filesystem()
+	cases := []struct {
+		name    string
+		dir     bool
+		removed bool
+	}{
+		{fs.TempName("interrupted"), false, true},
+		{fs.TempName(filepath.Join("dir", "interrupted")), false, true},
+		{fs.TempName("pulling"), false, false},
+		{fs.TempName("directory"), true, false},
+		{"regular", false, false},
+	}
+	if err := ffs.Mkdir("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	removed := 0
+	for _, tc := range cases {
+		if tc.dir {
+			if err := ffs.Mkdir(tc.name, 0755); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			createFile(t, tc.name, ffs)
+		}
+		if tc.removed {
+			removed++
+		}
+	}
+
+	if n, err := m.CleanTemporaries("default"); err != nil {
+		t.Fatal(err)
+	} else if n != removed {
+		t.Errorf("Expected %d removed files, got %d", removed, n)
+	}
+	for _, tc := range cases {
+		_, err := ffs.Lstat(tc.name)
+		if exists := err == nil; exists == tc.removed {
+			t.Errorf("%v: expected removed to be %v, got %v", tc.name, tc.removed, !exists)
+		}
+	}
+
+	if _, err := m.CleanTemporaries("missing"); err != errFolderMissing {
+		t.Errorf("Expected %v for a missing folder, got %v", errFolderMissing, err)
+	}
+}
+
+func TestFolderKeepTemporaries(t *testing.T) {
+	cases := []struct {
+		globalH int
+		folderH int
+		removed bool
+	}{
+		{24, 0, false},
+		{24, 1, true},
+		{1, 0, true},
+		{1, 24, false},
+	}
+	for _, tc := range cases {
+		w, fcfg := tmpDefaultWrapper()
+		opts := w.Options()
+		opts.KeepTemporariesH = tc.globalH
+		w.SetOptions(opts)
+		fcfg.KeepTemporariesH = tc.folderH
+		w.SetFolder(fcfg)
+
+		ffs := fcfg.Filesystem()
+		name := fs.TempName("file")
+		createFile(t, name, ffs)
+		old := time.Now().Add(-2 * time.Hour)
+		if err := ffs.Chtimes(name, old, old); err != nil {
+			t.Fatal(err)
+		}
+
+		m := setupModel(w)
+		if err := m.ScanFolder("default"); err != nil {
+			t.Error(err)
+		}
+		_, err := ffs.Lstat(name)
+		if removed := fs.IsNotExist(err); removed != tc.removed {
+			t.Errorf("global %dh, folder %dh: expected removed to be %v, got %v", tc.globalH, tc.folderH, tc.removed, removed)
+		}
+		cleanupModelAndRemoveDir(m, ffs.URI())
+	}
+}
+
+func TestNumConnections(t *testing.T) {
+	m, fc, fcfg := setupModelWithConnection()
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	if n := m.NumConnections(); n != 1 {
+		t.Fatal("Expected one connection, got", n)
+	}
+	m.Closed(fc, protocol.ErrTimeout)
+	if n := m.NumConnections(); n != 0 {
+		t.Fatal("Expected no connection after closing, got", n)
+	}
+}
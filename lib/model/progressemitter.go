@@ -270,6 +270,25 @@ func (t *ProgressEmitter) BytesCompleted(folder string) (bytes int64) {
 	return
 }
 
+// pullerProgresses returns a snapshot of the progress of all registered
+// pullers, keyed by folder and file name.
+func (t *ProgressEmitter) pullerProgresses() map[string]map[string]*pullerProgress {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	res := make(map[string]map[string]*pullerProgress, len(t.registry))
+	for folder, pullers := range t.registry {
+		if len(pullers) == 0 {
+			continue
+		}
+		res[folder] = make(map[string]*pullerProgress, len(pullers))
+		for name, puller := range pullers {
+			res[folder][name] = puller.Progress()
+		}
+	}
+	return res
+}
+
 func (t *ProgressEmitter) String() string {
 	return fmt.Sprintf("ProgressEmitter@%p", t)
 }
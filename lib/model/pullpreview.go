@@ -0,0 +1,89 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"fmt"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// PullPreview describes the changes pulling a folder would make to the local
+// files, as determined from the needed files without pulling anything.
+type PullPreview struct {
+	Download      []string `json:"download"`      // files, directories and symlinks to be created or updated
+	Delete        []string `json:"delete"`        // items to be deleted (or archived by the versioner)
+	Conflict      []string `json:"conflict"`      // files changed locally and remotely, to be kept as conflict copies
+	DownloadBytes int64    `json:"downloadBytes"` // size of the blocks to be fetched or copied
+}
+
+// PreviewPull returns the changes pulling the given folder would make at the
+// moment. Send only folders never pull and thus have no changes.
+func (m *model) PreviewPull(folder string) (PullPreview, error) {
+	m.fmut.RLock()
+	cfg, cfgOk := m.folderCfgs[folder]
+	fset, fsetOk := m.folderFiles[folder]
+	ignores := m.folderIgnores[folder]
+	m.fmut.RUnlock()
+
+	if !cfgOk || !fsetOk {
+		return PullPreview{}, fmt.Errorf("folder %s does not exist or is paused", folder)
+	}
+
+	preview := PullPreview{
+		Download: []string{},
+		Delete:   []string{},
+		Conflict: []string{},
+	}
+	if cfg.Type == config.FolderTypeSendOnly {
+		return preview, nil
+	}
+
+	filesystem := cfg.Filesystem()
+	fset.WithNeed(protocol.LocalDeviceID, func(intf db.FileIntf) bool {
+		file := intf.(protocol.FileInfo)
+		if cfg.IgnoreDelete && file.IsDeleted() {
+			return true
+		}
+		if ignores != nil && ignores.ShouldIgnore(file.Name) || cfg.IgnoreHidden && fs.IsHidden(filesystem, file.Name) {
+			// Will just be marked as ignored.
+			return true
+		}
+
+		// Like the puller, this takes invalid local items into account, e.g.
+		// the local changes of receive only folders.
+		cur, hasCur := fset.Get(protocol.LocalDeviceID, file.Name)
+		hasCur = hasCur && !cur.IsDeleted()
+		switch {
+		case file.IsDeleted():
+			// Deletions always lose conflicts, the local item is kept then.
+			if hasCur && !inConflict(cur.Version, file.Version, m.shortID) {
+				preview.Delete = append(preview.Delete, file.Name)
+			}
+		case hasCur && cur.Type == protocol.FileInfoTypeFile && inConflict(cur.Version, file.Version, m.shortID):
+			preview.Conflict = append(preview.Conflict, file.Name)
+			preview.DownloadBytes += file.Size
+		default:
+			preview.Download = append(preview.Download, file.Name)
+			if file.Type == protocol.FileInfoTypeFile {
+				if !hasCur {
+					preview.DownloadBytes += file.Size
+				} else {
+					_, need := blockDiff(cur.Blocks, file.Blocks)
+					for _, block := range need {
+						preview.DownloadBytes += int64(block.Size)
+					}
+				}
+			}
+		}
+		return true
+	})
+	return preview, nil
+}
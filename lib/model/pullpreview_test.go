@@ -0,0 +1,63 @@
+// Copyright (C) 2019 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestPreviewPull(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	// Re-add the folder without starting it, so nothing is pulled.
+	m.removeFolder(fcfg)
+	m.addFolder(fcfg)
+
+	m.fmut.RLock()
+	set := m.folderFiles[fcfg.ID]
+	m.fmut.RUnlock()
+
+	local := protocol.Vector{}.Update(myID.Short())
+	remote := local.Update(device1.Short())
+	concurrent := protocol.Vector{}.Update(device1.Short())
+	block := protocol.BlockInfo{Size: 10, Hash: []byte("hash")}
+
+	set.Update(protocol.LocalDeviceID, []protocol.FileInfo{
+		{Name: "deleted", Version: local, Size: 10, Blocks: []protocol.BlockInfo{block}},
+		{Name: "conflicting", Version: local, Size: 10, Blocks: []protocol.BlockInfo{block}},
+		{Name: "receiveonly", Version: local, Size: 10, Blocks: []protocol.BlockInfo{block}, LocalFlags: protocol.FlagLocalReceiveOnly},
+	})
+	set.Update(device1, []protocol.FileInfo{
+		{Name: "new", Version: concurrent, Size: 10, Blocks: []protocol.BlockInfo{block}},
+		{Name: "deleted", Version: remote, Deleted: true},
+		{Name: "conflicting", Version: concurrent, ModifiedS: 10, Size: 20, Blocks: []protocol.BlockInfo{block, block}},
+		{Name: "receiveonly", Version: concurrent, Size: 10, Blocks: []protocol.BlockInfo{block}},
+	})
+
+	preview, err := m.PreviewPull(fcfg.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := PullPreview{
+		Download:      []string{"new"},
+		Delete:        []string{"deleted"},
+		Conflict:      []string{"conflicting", "receiveonly"},
+		DownloadBytes: 40,
+	}
+	if !reflect.DeepEqual(preview, expected) {
+		t.Errorf("Got preview %+v, expected %+v", preview, expected)
+	}
+
+	if _, err := m.PreviewPull("doesnotexist"); err == nil {
+		t.Error("No error for nonexistent folder")
+	}
+}
@@ -7,7 +7,6 @@
 package model
 
 import (
-	"io"
 	"time"
 
 	"github.com/pkg/errors"
@@ -77,6 +76,23 @@ func (w *lockedWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
 	return w.fd.WriteAt(p, off)
 }
 
+// CopyRange copies size bytes from src at srcOffset to dstOffset using the
+// given method, see fs.CopyRange. The methods taking offsets are goroutine
+// safe like WriteAt and just need to acquire a read-lock. The sendfile
+// method, which may also be tried by CopyRangeMethodAllWithFallback, seeks
+// the fd and writes at its current offset though, thus needs to acquire a
+// write-lock to not interfere with other copies into the same file.
+func (w *lockedWriterAt) CopyRange(method fs.CopyRangeMethod, src fs.File, srcOffset, dstOffset, size int64) error {
+	if method == fs.CopyRangeMethodSendFile || method == fs.CopyRangeMethodAllWithFallback {
+		w.mut.Lock()
+		defer w.mut.Unlock()
+	} else {
+		w.mut.RLock()
+		defer w.mut.RUnlock()
+	}
+	return fs.CopyRange(method, src, w.fd, srcOffset, dstOffset, size)
+}
+
 // SyncClose ensures that no more writes are happening before going ahead and
 // syncing and closing the fd, thus needs to acquire a write-lock.
 func (w *lockedWriterAt) SyncClose() error {
@@ -92,7 +108,7 @@ func (w *lockedWriterAt) SyncClose() error {
 
 // tempFile returns the fd for the temporary file, reusing an open fd
 // or creating the file as necessary.
-func (s *sharedPullerState) tempFile() (io.WriterAt, error) {
+func (s *sharedPullerState) tempFile() (*lockedWriterAt, error) {
 	s.mut.Lock()
 	defer s.mut.Unlock()
 
@@ -4,7 +4,7 @@
 // License, v. 2.0. If a copy of the MPL was not distributed with this file,
 // You can obtain one at https://mozilla.org/MPL/2.0/.
 
-package main
+package osutil
 
 import (
 	"io"
@@ -33,7 +33,7 @@ func TestRotatedFile(t *testing.T) {
 	maxSize := int64(len(testData) + len(testData)/2)
 
 	// We allow the log file plus two rotated copies.
-	rf := newRotatedFile(logName, open, maxSize, 2)
+	rf := NewRotatedFile(logName, open, maxSize, 2)
 
 	// Write some bytes.
 	if _, err := rf.Write(testData); err != nil {
@@ -79,6 +79,43 @@ func TestRotatedFile(t *testing.T) {
 	checkNotExist(t, numberedFile(logName, 2)) // exceeds maxFiles so deleted
 }
 
+func TestRotatedFileError(t *testing.T) {
+	// Verify that failing to rotate is reported but doesn't prevent writing.
+
+	dir, err := ioutil.TempDir("", "syncthing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	open := func(name string) (io.WriteCloser, error) {
+		return os.Create(name)
+	}
+
+	logName := filepath.Join(dir, "log.txt")
+	testData := []byte("12345678\n")
+
+	// A non-empty directory in place of the first rotated file can't be
+	// replaced by renaming.
+	if err := os.MkdirAll(filepath.Join(numberedFile(logName, 0), "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	rf := NewRotatedFile(logName, open, int64(len(testData)), 1)
+	if _, err := rf.Write(testData); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := rf.Write(testData); err == nil {
+		t.Error("expected an error when rotating fails")
+	} else if n != len(testData) {
+		t.Errorf("expected %d bytes to be written, got %d", len(testData), n)
+	}
+	checkSize(t, logName, len(testData))
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestNumberedFile(t *testing.T) {
 	// Mostly just illustrates where the number ends up and makes sure it
 	// doesn't crash without an extension.
@@ -140,7 +177,7 @@ func TestAutoClosedFile(t *testing.T) {
 	data := []byte("hello, world\n")
 
 	// An autoclosed file that closes very quickly
-	ac := newAutoclosedFile(file, time.Millisecond, time.Millisecond)
+	ac := NewAutoclosedFile(file, time.Millisecond, time.Millisecond)
 
 	// Write some data.
 	if _, err := ac.Write(data); err != nil {
@@ -182,7 +219,7 @@ func TestAutoClosedFile(t *testing.T) {
 	}
 
 	// Open the file again.
-	ac = newAutoclosedFile(file, time.Second, time.Second)
+	ac = NewAutoclosedFile(file, time.Second, time.Second)
 
 	// Write something
 	if _, err := ac.Write(data); err != nil {
@@ -59,6 +59,9 @@ type Config struct {
 	ModTimeWindow time.Duration
 	// Event logger to which the scan progress events are sent
 	EventLogger events.Logger
+	// If IgnoreHidden is true, files hidden by the operating system and
+	// well known system files are treated as ignored.
+	IgnoreHidden bool
 }
 
 type CurrentFiler interface {
@@ -118,6 +121,12 @@ func (w *walker) walk(ctx context.Context) chan ScanResult {
 					l.Debugf("Skip walking %v as it is below a symlink", sub)
 					continue
 				}
+				// Only the walked files themselves are checked for being
+				// hidden, so check the directories containing them here.
+				if w.IgnoreHidden && fs.IsHidden(w.Filesystem, filepath.Dir(sub)) {
+					l.Debugf("Skip walking %v as it is below a hidden directory", sub)
+					continue
+				}
 				w.Filesystem.Walk(sub, hashFiles)
 			}
 		}
@@ -241,6 +250,11 @@ func (w *walker) walkAndHashFiles(ctx context.Context, toHashChan chan<- protoco
 			return skip
 		}
 
+		if w.IgnoreHidden && fs.IsHiddenFile(w.Filesystem, path, info) {
+			l.Debugln("ignored (hidden):", path)
+			return skip
+		}
+
 		if w.Matcher.Match(path).IsIgnored() {
 			l.Debugln("ignored (patterns):", path)
 			// Only descend if matcher says so and the current file is not a symlink.
@@ -665,6 +665,51 @@ func TestIssue4799(t *testing.T) {
 	}
 }
 
+func TestWalkIgnoreHidden(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	fs := fs.NewFilesystem(fs.FilesystemTypeBasic, tmp)
+	for _, name := range []string{"foo", "Thumbs.db", "desktop.ini"} {
+		fd, err := fs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+	}
+
+	cfg := testConfig()
+	cfg.Filesystem = fs
+	cfg.IgnoreHidden = true
+	var names []string
+	for f := range Walk(context.TODO(), cfg) {
+		if f.Err == nil && f.File.Name != "." {
+			names = append(names, f.File.Name)
+		}
+	}
+	if len(names) != 1 || names[0] != "foo" {
+		t.Error("Hidden files should not have been scanned:", names)
+	}
+
+	// Walking a subdirectory of a hidden directory.
+	sub := filepath.Join("$RECYCLE.BIN", "sub")
+	if err := fs.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := fs.Create(filepath.Join(sub, "bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+	cfg.Subs = []string{sub}
+	for f := range Walk(context.TODO(), cfg) {
+		t.Error("Files below a hidden directory should not have been scanned:", f.File.Name, f.Err)
+	}
+}
+
 func TestRecurseInclude(t *testing.T) {
 	stignore := `
 	!/dir1/cfile
@@ -8,10 +8,12 @@ package syncthing
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"runtime"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
@@ -37,6 +39,11 @@ import (
 	"github.com/syncthing/syncthing/lib/ur"
 )
 
+var (
+	errForceStopped   = errors.New("forcefully stopped")
+	errStartupAborted = errors.New("startup aborted")
+)
+
 const (
 	bepProtocolName        = "bep/1.0"
 	tlsDefaultCommonName   = "syncthing"
@@ -64,10 +71,17 @@ type Options struct {
 	AssetDir         string
 	AuditWriter      io.Writer
 	DeadlockTimeoutS int
+	Locations        *locations.Locations // nil means the process wide default locations
 	NoUpgrade        bool
 	ProfilerURL      string
 	ResetDeltaIdxs   bool
 	Verbose          bool
+	// OnPanic, if set, is called with the recovered value and the stack
+	// trace when starting up or the Serve method of one of the main services
+	// panics; the app is then stopped with ExitError instead of the panic
+	// crashing the process. Panics in other goroutines, e.g. the ones started
+	// by services, still crash the process.
+	OnPanic func(recovered interface{}, stack []byte)
 }
 
 type App struct {
@@ -77,41 +91,89 @@ type App struct {
 	ll          *db.Lowlevel
 	evLogger    events.Logger
 	cert        tls.Certificate
+	locations   *locations.Locations
 	opts        Options
 	exitStatus  ExitStatus
 	err         error
 	stopOnce    sync.Once
 	stop        chan struct{}
+	forceOnce   sync.Once
+	force       chan struct{}
+
+	// mut protects the fields below, which are set while starting up and
+	// may be accessed concurrently via the accessors.
+	mut         sync.Mutex
+	m           model.Model
+	discoverer  discover.CachingMux
+	connections connections.Service
+	ur          *ur.Service
+	api         api.Service
 	stopped     chan struct{}
+	released    chan struct{}
 }
 
 func New(cfg config.Wrapper, dbBackend backend.Backend, evLogger events.Logger, cert tls.Certificate, opts Options) *App {
 	a := &App{
-		cfg:      cfg,
-		ll:       db.NewLowlevel(dbBackend),
-		evLogger: evLogger,
-		opts:     opts,
-		cert:     cert,
-		stop:     make(chan struct{}),
-		stopped:  make(chan struct{}),
+		cfg:       cfg,
+		ll:        db.NewLowlevel(dbBackend),
+		evLogger:  evLogger,
+		opts:      opts,
+		cert:      cert,
+		locations: opts.Locations,
+		stop:      make(chan struct{}),
+		force:     make(chan struct{}),
+		stopped:   make(chan struct{}),
+		released:  make(chan struct{}),
+	}
+	if a.locations == nil {
+		a.locations = locations.Default()
 	}
 	close(a.stopped) // Hasn't been started, so shouldn't block on Wait.
+	close(a.released)
 	return a
 }
 
 // Start executes the app and returns once all the startup operations are done,
-// e.g. the API is ready for use.
+// e.g. the API is ready for use. If the app is stopped meanwhile, the startup
+// is aborted and an error is returned.
 // Must be called once only.
 func (a *App) Start() error {
-	if err := a.startup(); err != nil {
+	a.mut.Lock()
+	a.stopped = make(chan struct{})
+	a.released = make(chan struct{})
+	a.mut.Unlock()
+	err := a.startupRecovering()
+	go a.run()
+	if err != nil {
 		a.stopWithErr(ExitError, err)
 		return err
 	}
-	a.stopped = make(chan struct{})
-	go a.run()
 	return nil
 }
 
+// startupRecovering calls startup, turning a panic into an error if
+// Options.OnPanic is set.
+func (a *App) startupRecovering() (err error) {
+	if a.opts.OnPanic != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				a.opts.OnPanic(r, debug.Stack())
+				err = fmt.Errorf("panic while starting up: %v", r)
+			}
+		}()
+	}
+	return a.startup()
+}
+
+// addService adds the given service to the main service, recovering panics
+// of its Serve method if Options.OnPanic is set.
+func (a *App) addService(svc suture.Service) {
+	if a.opts.OnPanic != nil {
+		svc = &panicRecoveringService{Service: svc, app: a}
+	}
+	a.mainService.Add(svc)
+}
+
 func (a *App) startup() error {
 	// Create a main service manager. We'll add things to this as we go along.
 	// We want any logging it does to go through our log system.
@@ -124,11 +186,11 @@ func (a *App) startup() error {
 	a.mainService.ServeBackground()
 
 	if a.opts.AuditWriter != nil {
-		a.mainService.Add(newAuditService(a.opts.AuditWriter, a.evLogger))
+		a.addService(newAuditService(a.opts.AuditWriter, a.evLogger))
 	}
 
 	if a.opts.Verbose {
-		a.mainService.Add(newVerboseService(a.evLogger))
+		a.addService(newVerboseService(a.evLogger))
 	}
 
 	errors := logger.NewRecorder(l, logger.LevelWarn, maxSystemErrors, 0)
@@ -158,7 +220,7 @@ func (a *App) startup() error {
 	// Emit the Starting event, now that we know who we are.
 
 	a.evLogger.Log(events.Starting, map[string]string{
-		"home": locations.GetBaseDir(locations.ConfigBaseDir),
+		"home": a.locations.GetBaseDir(locations.ConfigBaseDir),
 		"myID": a.myID.String(),
 	})
 
@@ -179,9 +241,17 @@ func (a *App) startup() error {
 		}()
 	}
 
+	if err := a.checkAborted(); err != nil {
+		return err
+	}
+
 	perf := ur.CpuBench(3, 150*time.Millisecond, true)
 	l.Infof("Hashing performance is %.02f MB/s", perf)
 
+	if err := a.checkAborted(); err != nil {
+		return err
+	}
+
 	if err := db.UpdateSchema(a.ll); err != nil {
 		l.Warnln("Database schema:", err)
 		return err
@@ -193,10 +263,10 @@ func (a *App) startup() error {
 	}
 
 	protectedFiles := []string{
-		locations.Get(locations.Database),
-		locations.Get(locations.ConfigFile),
-		locations.Get(locations.CertFile),
-		locations.Get(locations.KeyFile),
+		a.locations.Get(locations.Database),
+		a.locations.Get(locations.ConfigFile),
+		a.locations.Get(locations.CertFile),
+		a.locations.Get(locations.KeyFile),
 	}
 
 	// Remove database entries for folders that no longer exist in the config
@@ -236,7 +306,11 @@ func (a *App) startup() error {
 		miscDB.PutString("prevVersion", build.Version)
 	}
 
-	m := model.NewModel(a.cfg, a.myID, "syncthing", build.Version, a.ll, protectedFiles, a.evLogger)
+	if err := a.checkAborted(); err != nil {
+		return err
+	}
+
+	m := model.NewModel(a.cfg, a.myID, "syncthing", build.Version, a.ll, a.locations.Get(locations.Database), protectedFiles, a.evLogger)
 
 	if a.opts.DeadlockTimeoutS > 0 {
 		m.StartDeadlockDetector(time.Duration(a.opts.DeadlockTimeoutS) * time.Second)
@@ -244,12 +318,18 @@ func (a *App) startup() error {
 		m.StartDeadlockDetector(20 * time.Minute)
 	}
 
-	a.mainService.Add(m)
+	a.addService(m)
+	a.mut.Lock()
+	a.m = m
+	a.mut.Unlock()
 
 	// Start discovery
 
 	cachedDiscovery := discover.NewCachingMux()
-	a.mainService.Add(cachedDiscovery)
+	a.addService(cachedDiscovery)
+	a.mut.Lock()
+	a.discoverer = cachedDiscovery
+	a.mut.Unlock()
 
 	// The TLS configuration is used for both the listening socket and outgoing
 	// connections.
@@ -264,7 +344,10 @@ func (a *App) startup() error {
 	// Start connection management
 
 	connectionsService := connections.NewService(a.cfg, a.myID, m, tlsCfg, cachedDiscovery, bepProtocolName, tlsDefaultCommonName, a.evLogger)
-	a.mainService.Add(connectionsService)
+	a.addService(connectionsService)
+	a.mut.Lock()
+	a.connections = connectionsService
+	a.mut.Unlock()
 
 	if a.cfg.Options().GlobalAnnEnabled {
 		for _, srv := range a.cfg.Options().GlobalDiscoveryServers() {
@@ -319,7 +402,14 @@ func (a *App) startup() error {
 	}
 
 	usageReportingSvc := ur.New(a.cfg, m, connectionsService, a.opts.NoUpgrade)
-	a.mainService.Add(usageReportingSvc)
+	a.addService(usageReportingSvc)
+	a.mut.Lock()
+	a.ur = usageReportingSvc
+	a.mut.Unlock()
+
+	if err := a.checkAborted(); err != nil {
+		return err
+	}
 
 	// GUI
 
@@ -340,6 +430,10 @@ func (a *App) startup() error {
 		l.Warnln("Syncthing should not run as a privileged or system user. Please consider using a normal user account.")
 	}
 
+	if err := a.checkAborted(); err != nil {
+		return err
+	}
+
 	a.evLogger.Log(events.StartupComplete, map[string]string{
 		"myID": a.myID.String(),
 	})
@@ -353,10 +447,40 @@ func (a *App) startup() error {
 	return nil
 }
 
+// checkAborted returns an error if the app has been stopped while starting up.
+func (a *App) checkAborted() error {
+	select {
+	case <-a.stop:
+		l.Infoln("Startup aborted")
+		return errStartupAborted
+	default:
+		return nil
+	}
+}
+
 func (a *App) run() {
 	<-a.stop
 
-	a.mainService.Stop()
+	released := a.releasedChan()
+	go a.shutdown(released)
+	select {
+	case <-released:
+	case <-a.force:
+		l.Warnln("Not waiting for the shutdown to complete")
+	}
+
+	l.Infoln("Exiting")
+
+	close(a.stoppedChan())
+}
+
+// shutdown stops the services and closes the database, then closes released.
+func (a *App) shutdown(released chan struct{}) {
+	defer close(released)
+
+	if a.mainService != nil {
+		a.mainService.Stop()
+	}
 
 	done := make(chan struct{})
 	go func() {
@@ -368,19 +492,75 @@ func (a *App) run() {
 	case <-time.After(10 * time.Second):
 		l.Warnln("Database failed to stop within 10s")
 	}
-
-	l.Infoln("Exiting")
-
-	close(a.stopped)
 }
 
 // Wait blocks until the app stops running. Also returns if the app hasn't been
 // started yet.
 func (a *App) Wait() ExitStatus {
-	<-a.stopped
+	<-a.stoppedChan()
 	return a.exitStatus
 }
 
+func (a *App) stoppedChan() chan struct{} {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.stopped
+}
+
+// Released returns a channel which is closed once the services of the app
+// have stopped and the database has been closed, or closing it has timed out.
+// Unless the app has been stopped via ForceStop this happens before Wait
+// returns. It is closed already if the app hasn't been started yet.
+func (a *App) Released() <-chan struct{} {
+	return a.releasedChan()
+}
+
+func (a *App) releasedChan() chan struct{} {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.released
+}
+
+// Model returns the model of the app. It returns nil if the app hasn't been
+// started yet.
+func (a *App) Model() model.Model {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.m
+}
+
+// Discoverer returns the discovery cache of the app. It returns nil if the
+// app hasn't been started yet.
+func (a *App) Discoverer() discover.CachingMux {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.discoverer
+}
+
+// ConnectionsService returns the connections service of the app. It returns
+// nil if the app hasn't been started yet.
+func (a *App) ConnectionsService() connections.Service {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.connections
+}
+
+// UsageReportingService returns the usage reporting service of the app. It
+// returns nil if the app hasn't been started yet.
+func (a *App) UsageReportingService() *ur.Service {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.ur
+}
+
+// APIService returns the GUI/REST API service of the app. It returns nil if
+// the app hasn't been started yet or the GUI is disabled.
+func (a *App) APIService() api.Service {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.api
+}
+
 // Error returns an error if one occurred while running the app. It does not wait
 // for the app to stop before returning.
 func (a *App) Error() error {
@@ -398,13 +578,24 @@ func (a *App) Stop(stopReason ExitStatus) ExitStatus {
 	return a.stopWithErr(stopReason, nil)
 }
 
+// ForceStop stops the app like Stop but doesn't wait for the services to stop
+// and the database to be closed, e.g. because a service hangs. This continues
+// in the background; use Released to wait for it. If the app wasn't stopped
+// before, its exit status is ExitError. It returns the effective exit status.
+func (a *App) ForceStop() ExitStatus {
+	a.forceOnce.Do(func() {
+		close(a.force)
+	})
+	return a.stopWithErr(ExitError, errForceStopped)
+}
+
 func (a *App) stopWithErr(stopReason ExitStatus, err error) ExitStatus {
 	a.stopOnce.Do(func() {
 		a.exitStatus = stopReason
 		a.err = err
 		close(a.stop)
 	})
-	<-a.stopped
+	<-a.stoppedChan()
 	return a.exitStatus
 }
 
@@ -420,13 +611,16 @@ func (a *App) setupGUI(m model.Model, defaultSub, diskSub events.BufferedSubscri
 	}
 
 	cpu := newCPUService()
-	a.mainService.Add(cpu)
+	a.addService(cpu)
 
 	summaryService := model.NewFolderSummaryService(a.cfg, m, a.myID, a.evLogger)
-	a.mainService.Add(summaryService)
+	a.addService(summaryService)
 
-	apiSvc := api.New(a.myID, a.cfg, a.opts.AssetDir, tlsDefaultCommonName, m, defaultSub, diskSub, a.evLogger, discoverer, connectionsService, urService, summaryService, errors, systemLog, cpu, &controller{a}, a.opts.NoUpgrade)
-	a.mainService.Add(apiSvc)
+	apiSvc := api.New(a.myID, a.cfg, a.opts.AssetDir, tlsDefaultCommonName, a.locations, m, defaultSub, diskSub, a.evLogger, discoverer, connectionsService, urService, summaryService, errors, systemLog, cpu, &controller{a}, a.opts.NoUpgrade)
+	a.addService(apiSvc)
+	a.mut.Lock()
+	a.api = apiSvc
+	a.mut.Unlock()
 
 	if err := apiSvc.WaitForStart(); err != nil {
 		return err
@@ -463,3 +657,25 @@ func (e *controller) Shutdown() {
 func (e *controller) ExitUpgrading() {
 	e.Stop(ExitUpgrade)
 }
+
+// panicRecoveringService passes panics of the Serve method of the wrapped
+// service to Options.OnPanic and stops the app.
+type panicRecoveringService struct {
+	suture.Service
+	app *App
+}
+
+func (s *panicRecoveringService) Serve() {
+	defer func() {
+		if r := recover(); r != nil {
+			s.app.opts.OnPanic(r, debug.Stack())
+			// Stopping waits for the main service, which runs this.
+			go s.app.stopWithErr(ExitError, fmt.Errorf("panic in %v: %v", s.Service, r))
+		}
+	}()
+	s.Service.Serve()
+}
+
+func (s *panicRecoveringService) String() string {
+	return fmt.Sprint(s.Service)
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/db/backend"
 	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/locations"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/tlsutil"
 )
@@ -105,3 +106,188 @@ func TestStartupFail(t *testing.T) {
 		t.Errorf(`Got different errors "%v" from Start and "%v" from Error`, startErr, err)
 	}
 }
+
+// newTestApp returns an app within the given directory which neither
+// listens nor announces itself, so it can be started within tests.
+func newTestApp(t *testing.T, tmpDir string, ldb backend.Backend) *App {
+	return newTestAppWithOptions(t, tmpDir, ldb, Options{})
+}
+
+func newTestAppWithOptions(t *testing.T, tmpDir string, ldb backend.Backend, opts Options) *App {
+	t.Helper()
+	cert, err := tlsutil.NewCertificate(filepath.Join(tmpDir, "cert"), filepath.Join(tmpDir, "key"), "syncthing", 365)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := config.New(protocol.NewDeviceID(cert.Certificate[0]))
+	raw.GUI.Enabled = false
+	raw.Options.RawListenAddresses = nil
+	raw.Options.GlobalAnnEnabled = false
+	raw.Options.LocalAnnEnabled = false
+	cfg := config.Wrap(filepath.Join(tmpDir, "config.xml"), raw, events.NoopLogger)
+
+	locs, err := locations.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := locs.SetBaseDir(locations.ConfigBaseDir, tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	opts.Locations = locs
+	return New(cfg, ldb, events.NoopLogger, cert, opts)
+}
+
+func TestAccessorsWhileStarting(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "syncthing-TestAccessorsWhileStarting-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	app := newTestApp(t, tmpDir, backend.OpenMemory())
+
+	// Poll the accessors while starting up; run with -race to detect
+	// unsynchronized access.
+	started := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		for {
+			app.Model()
+			app.Discoverer()
+			app.ConnectionsService()
+			app.UsageReportingService()
+			app.APIService()
+			select {
+			case <-started:
+				return
+			default:
+			}
+		}
+	}()
+	err = app.Start()
+	close(started)
+	<-polled
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Stop(ExitSuccess)
+
+	if app.Model() == nil {
+		t.Error("Model is nil after starting")
+	}
+	if app.APIService() != nil {
+		t.Error("API service is set although the GUI is disabled")
+	}
+}
+
+func TestForceStop(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "syncthing-TestForceStop-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ldb := backend.OpenMemory()
+	app := newTestApp(t, tmpDir, ldb)
+	if err := app.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An open transaction makes closing the database block.
+	tran, err := ldb.NewReadTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan ExitStatus)
+	go func() {
+		done <- app.ForceStop()
+	}()
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("ForceStop did not return within 5s")
+	case status := <-done:
+		if status != ExitError {
+			t.Errorf("Got exit status %v, expected %v", status, ExitError)
+		}
+	}
+	select {
+	case <-app.Released():
+		t.Fatal("Released closed while the database is in use")
+	default:
+	}
+
+	tran.Release()
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("Released not closed within 5s after releasing the database")
+	case <-app.Released():
+	}
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "syncthing-TestStopBeforeStart-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ldb := backend.OpenMemory()
+	app := newTestApp(t, tmpDir, ldb)
+	app.Stop(ExitSuccess)
+	if err := app.Start(); err != errStartupAborted {
+		t.Fatalf("Got error %v from Start, expected %v", err, errStartupAborted)
+	}
+	if status := app.Wait(); status != ExitSuccess {
+		t.Errorf("Got exit status %v, expected %v", status, ExitSuccess)
+	}
+	<-app.Released()
+	if _, err := ldb.NewReadTransaction(); err == nil {
+		t.Error("Database still open after aborting the startup")
+	}
+}
+
+type panickingService struct{}
+
+func (panickingService) Serve() { panic("test panic") }
+func (panickingService) Stop()  {}
+
+func TestPanicInService(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "syncthing-TestPanicInService-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	recovered := make(chan interface{}, 1)
+	app := newTestAppWithOptions(t, tmpDir, backend.OpenMemory(), Options{
+		OnPanic: func(r interface{}, stack []byte) {
+			if len(stack) == 0 {
+				t.Error("Got no stack trace")
+			}
+			select {
+			case recovered <- r:
+			default:
+			}
+		},
+	})
+	if err := app.Start(); err != nil {
+		t.Fatal(err)
+	}
+	app.addService(panickingService{})
+
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnPanic not called within 5s")
+	case r := <-recovered:
+		if r != "test panic" {
+			t.Errorf("Got recovered value %v, expected the panic", r)
+		}
+	}
+	if status := app.Wait(); status != ExitError {
+		t.Errorf("Got exit status %v, expected %v", status, ExitError)
+	}
+	if app.Error() == nil {
+		t.Error("Got no error after the panic")
+	}
+}
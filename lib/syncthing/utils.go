@@ -24,24 +24,32 @@ import (
 	"github.com/syncthing/syncthing/lib/tlsutil"
 )
 
-func LoadOrGenerateCertificate(certFile, keyFile string) (tls.Certificate, error) {
-	cert, err := tls.LoadX509KeyPair(
-		locations.Get(locations.CertFile),
-		locations.Get(locations.KeyFile),
-	)
+// LoadOrGenerateCertificate loads the certificate from the given files or
+// generates a new one with the given common name and key type if that fails.
+// An empty common name means the default one.
+func LoadOrGenerateCertificate(certFile, keyFile, commonName string, keyType tlsutil.KeyType) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	if err != nil {
-		l.Infof("Generating ECDSA key and certificate for %s...", tlsDefaultCommonName)
-		return tlsutil.NewCertificate(
-			locations.Get(locations.CertFile),
-			locations.Get(locations.KeyFile),
-			tlsDefaultCommonName,
+		if commonName == "" {
+			commonName = tlsDefaultCommonName
+		}
+		l.Infof("Generating %v key and certificate for %s...", keyType, commonName)
+		return tlsutil.NewCertificateWithKeyType(
+			certFile,
+			keyFile,
+			commonName,
 			deviceCertLifetimeDays,
+			keyType,
 		)
 	}
 	return cert, nil
 }
 
 func DefaultConfig(path string, myID protocol.DeviceID, evLogger events.Logger, noDefaultFolder bool) (config.Wrapper, error) {
+	return defaultConfig(path, locations.Get(locations.DefFolder), myID, evLogger, noDefaultFolder)
+}
+
+func defaultConfig(path, defaultFolderPath string, myID protocol.DeviceID, evLogger events.Logger, noDefaultFolder bool) (config.Wrapper, error) {
 	newCfg, err := config.NewWithFreePorts(myID)
 	if err != nil {
 		return nil, err
@@ -52,7 +60,7 @@ func DefaultConfig(path string, myID protocol.DeviceID, evLogger events.Logger,
 		return config.Wrap(path, newCfg, evLogger), nil
 	}
 
-	newCfg.Folders = append(newCfg.Folders, config.NewFolderConfiguration(myID, "default", "Default Folder", fs.FilesystemTypeBasic, locations.Get(locations.DefFolder)))
+	newCfg.Folders = append(newCfg.Folders, config.NewFolderConfiguration(myID, "default", "Default Folder", fs.FilesystemTypeBasic, defaultFolderPath))
 	l.Infoln("Default folder created and/or linked to new config")
 	return config.Wrap(path, newCfg, evLogger), nil
 }
@@ -62,10 +70,21 @@ func DefaultConfig(path string, myID protocol.DeviceID, evLogger events.Logger,
 // Otherwise it checks the version, and archives and upgrades the config if
 // necessary or returns an error, if the version isn't compatible.
 func LoadConfigAtStartup(path string, cert tls.Certificate, evLogger events.Logger, allowNewerConfig, noDefaultFolder bool) (config.Wrapper, error) {
+	return loadConfigAtStartup(path, locations.Get(locations.DefFolder), cert, evLogger, allowNewerConfig, noDefaultFolder)
+}
+
+// LoadConfigAtStartupWithLocations is like LoadConfigAtStartup but takes the
+// config file and the path of the default folder from the given locations
+// instead of the process wide default ones.
+func LoadConfigAtStartupWithLocations(locs *locations.Locations, cert tls.Certificate, evLogger events.Logger, allowNewerConfig, noDefaultFolder bool) (config.Wrapper, error) {
+	return loadConfigAtStartup(locs.Get(locations.ConfigFile), locs.Get(locations.DefFolder), cert, evLogger, allowNewerConfig, noDefaultFolder)
+}
+
+func loadConfigAtStartup(path, defaultFolderPath string, cert tls.Certificate, evLogger events.Logger, allowNewerConfig, noDefaultFolder bool) (config.Wrapper, error) {
 	myID := protocol.NewDeviceID(cert.Certificate[0])
 	cfg, err := config.Load(path, myID, evLogger)
 	if fs.IsNotExist(err) {
-		cfg, err = DefaultConfig(path, myID, evLogger, noDefaultFolder)
+		cfg, err = defaultConfig(path, defaultFolderPath, myID, evLogger, noDefaultFolder)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to generate default config")
 		}
@@ -126,3 +145,10 @@ func copyFile(src, dst string) error {
 func OpenDBBackend(path string, tuning config.Tuning) (backend.Backend, error) {
 	return backend.Open(path, backend.Tuning(tuning))
 }
+
+// OpenDBBackendWithRepairHandler is like OpenDBBackend but calls the given
+// handler when the database is corrupted and needs to be repaired while
+// opening it.
+func OpenDBBackendWithRepairHandler(path string, tuning config.Tuning, handler backend.RepairHandler) (backend.Backend, error) {
+	return backend.OpenWithRepairHandler(path, backend.Tuning(tuning), handler)
+}
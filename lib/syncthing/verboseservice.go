@@ -184,6 +184,10 @@ func (s *verboseService) formatEvent(ev events.Event) string {
 			success = "failed"
 		}
 		return fmt.Sprintf("Login %s for username %s.", success, username)
+
+	case events.ConnectionFailed:
+		data := ev.Data.(map[string]string)
+		return fmt.Sprintf("Connection to device %s at %s failed (%s): %s", data["device"], data["address"], data["class"], data["error"])
 	}
 
 	return fmt.Sprintf("%s %#v", ev.Type, ev)
@@ -90,9 +90,51 @@ func SecureDefault() *tls.Config {
 	}
 }
 
-// NewCertificate generates and returns a new TLS certificate.
+// A KeyType is the kind of key generated for a new certificate.
+type KeyType int
+
+const (
+	KeyTypeECDSAP384 KeyType = iota // the default
+	KeyTypeECDSAP256
+	KeyTypeRSA2048
+)
+
+func (t KeyType) String() string {
+	switch t {
+	case KeyTypeECDSAP384:
+		return "ECDSA P-384"
+	case KeyTypeECDSAP256:
+		return "ECDSA P-256"
+	case KeyTypeRSA2048:
+		return "RSA 2048"
+	default:
+		return fmt.Sprintf("unknown key type %d", int(t))
+	}
+}
+
+// NewCertificate generates and returns a new TLS certificate with an ECDSA
+// P-384 key.
 func NewCertificate(certFile, keyFile, commonName string, lifetimeDays int) (tls.Certificate, error) {
-	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	return NewCertificateWithKeyType(certFile, keyFile, commonName, lifetimeDays, KeyTypeECDSAP384)
+}
+
+// NewCertificateWithKeyType generates and returns a new TLS certificate with
+// a key of the given type.
+func NewCertificateWithKeyType(certFile, keyFile, commonName string, lifetimeDays int, keyType KeyType) (tls.Certificate, error) {
+	var priv interface{}
+	var err error
+	sigAlgo := x509.ECDSAWithSHA256
+	switch keyType {
+	case KeyTypeECDSAP384:
+		priv, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyTypeECDSAP256:
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeRSA2048:
+		priv, err = rsa.GenerateKey(rand.Reader, 2048)
+		sigAlgo = x509.SHA256WithRSA
+	default:
+		return tls.Certificate{}, errors.New(keyType.String())
+	}
 	if err != nil {
 		return tls.Certificate{}, errors.Wrap(err, "generate key")
 	}
@@ -109,7 +151,7 @@ func NewCertificate(certFile, keyFile, commonName string, lifetimeDays int) (tls
 		},
 		NotBefore:             notBefore,
 		NotAfter:              notAfter,
-		SignatureAlgorithm:    x509.ECDSAWithSHA256,
+		SignatureAlgorithm:    sigAlgo,
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		BasicConstraintsValid: true,
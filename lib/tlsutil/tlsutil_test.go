@@ -11,9 +11,15 @@ package tlsutil
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
 	"crypto/tls"
+	"crypto/x509"
 	"io"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -156,3 +162,53 @@ func (f *fakeConn) RemoteAddr() net.Addr             { return nil }
 func (f *fakeConn) SetDeadline(time.Time) error      { return nil }
 func (f *fakeConn) SetReadDeadline(time.Time) error  { return nil }
 func (f *fakeConn) SetWriteDeadline(time.Time) error { return nil }
+
+func TestNewCertificateWithKeyType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		keyType KeyType
+		name    string
+		rsa     bool
+		bits    int
+	}{
+		{KeyTypeECDSAP384, "ECDSA P-384", false, 384},
+		{KeyTypeECDSAP256, "ECDSA P-256", false, 256},
+		{KeyTypeRSA2048, "RSA 2048", true, 2048},
+	}
+	for _, tc := range cases {
+		if name := tc.keyType.String(); name != tc.name {
+			t.Errorf("key type %d: unexpected name %q", tc.keyType, name)
+		}
+		crt, err := NewCertificateWithKeyType(filepath.Join(dir, "crt"), filepath.Join(dir, "key"), "syncthing", 30, tc.keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := x509.ParseCertificate(crt.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var bits int
+		switch key := parsed.PublicKey.(type) {
+		case *ecdsa.PublicKey:
+			if !tc.rsa {
+				bits = key.Curve.Params().BitSize
+			}
+		case *rsa.PublicKey:
+			if tc.rsa {
+				bits = key.N.BitLen()
+			}
+		}
+		if bits != tc.bits {
+			t.Errorf("key type %v: unexpected public key %T", tc.keyType, parsed.PublicKey)
+		}
+	}
+
+	if _, err := NewCertificateWithKeyType(filepath.Join(dir, "crt"), filepath.Join(dir, "key"), "syncthing", 30, KeyType(-1)); err == nil {
+		t.Error("expected an error for an unknown key type")
+	}
+}
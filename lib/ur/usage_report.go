@@ -12,6 +12,8 @@ import (
 	"crypto/rand"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"runtime"
@@ -40,6 +42,8 @@ const Version = 3
 
 var StartTime = time.Now()
 
+var errDisabled = errors.New("usage reporting is disabled")
+
 type Service struct {
 	suture.Service
 	cfg                config.Wrapper
@@ -363,8 +367,18 @@ func (s *Service) UptimeS() int {
 	return int(time.Since(StartTime).Seconds())
 }
 
-func (s *Service) sendUsageReport() error {
+// SendReport assembles a usage report and sends it right away, regardless
+// of when the next report is scheduled. It returns the data which was sent or
+// an error if usage reporting is disabled or sending failed.
+func (s *Service) SendReport() (map[string]interface{}, error) {
+	if s.cfg.Options().URAccepted < 2 {
+		return nil, errDisabled
+	}
 	d := s.ReportData()
+	return d, s.sendUsageReport(d)
+}
+
+func (s *Service) sendUsageReport(d map[string]interface{}) error {
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(d); err != nil {
 		return err
@@ -379,8 +393,15 @@ func (s *Service) sendUsageReport() error {
 			},
 		},
 	}
-	_, err := client.Post(s.cfg.Options().URURL, "application/json", &b)
-	return err
+	resp, err := client.Post(s.cfg.Options().URURL, "application/json", &b)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	return nil
 }
 
 func (s *Service) serve(ctx context.Context) {
@@ -396,7 +417,7 @@ func (s *Service) serve(ctx context.Context) {
 			t.Reset(0)
 		case <-t.C:
 			if s.cfg.Options().URAccepted >= 2 {
-				err := s.sendUsageReport()
+				err := s.sendUsageReport(s.ReportData())
 				if err != nil {
 					l.Infoln("Usage report:", err)
 				} else {